@@ -71,7 +71,10 @@ func main() {
 				GENERATED_NUMBERS_MAXIMUM_VALUE-GENERATED_NUMBERS_MINIMUM_VALUE,
 			) + GENERATED_NUMBERS_MINIMUM_VALUE
 			message_to_send := fmt.Sprintf("{\"name\": \"%s\", \"value\": %d}", STREAM_PUBLISHER_NAME, generated_number)
-			err := producer.Send(amqp.NewMessage([]byte(message_to_send)))
+			message := amqp.NewMessage([]byte(message_to_send))
+			// the creation time places the message in the time range of the history of the datasource
+			message.Properties = &amqp.MessageProperties{CreationTime: time.Now()}
+			err := producer.Send(message)
 			CheckErr(err)
 			time.Sleep(100 * time.Millisecond)
 		}
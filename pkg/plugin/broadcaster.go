@@ -0,0 +1,251 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+const (
+	// SLOW_SUBSCRIBER_DROP drops the frames that arrive while a subscriber is still sending.
+	SLOW_SUBSCRIBER_DROP = "drop"
+	// SLOW_SUBSCRIBER_BUFFER buffers the frames of a subscriber and drops the oldest one once the buffer is full.
+	SLOW_SUBSCRIBER_BUFFER = "buffer"
+
+	DEFAULT_SUBSCRIBER_BUFFER_SIZE = 1000
+)
+
+type LiveOptions struct {
+	SlowSubscriberPolicy string `json:"slowSubscriberPolicy"`
+	SubscriberBufferSize int    `json:"subscriberBufferSize"`
+}
+
+func NewLiveOptions() *LiveOptions {
+	return &LiveOptions{
+		SlowSubscriberPolicy: SLOW_SUBSCRIBER_BUFFER,
+		SubscriberBufferSize: DEFAULT_SUBSCRIBER_BUFFER_SIZE,
+	}
+}
+
+func (liveOptions *LiveOptions) getPolicy() string {
+	if liveOptions.SlowSubscriberPolicy == SLOW_SUBSCRIBER_DROP {
+		return SLOW_SUBSCRIBER_DROP
+	}
+	return SLOW_SUBSCRIBER_BUFFER
+}
+
+func (liveOptions *LiveOptions) getBufferSize() int {
+	if liveOptions.getPolicy() == SLOW_SUBSCRIBER_DROP {
+		return 1
+	}
+	if liveOptions.SubscriberBufferSize <= 0 {
+		return DEFAULT_SUBSCRIBER_BUFFER_SIZE
+	}
+	return liveOptions.SubscriberBufferSize
+}
+
+// ConsumeFunc runs the consumer of a channel and broadcasts its frames until the context is canceled.
+type ConsumeFunc func(ctx context.Context, broadcaster *Broadcaster) error
+
+// Broadcasters holds a single broadcaster per live channel path, so every
+// subscriber of the same channel is fed by one consumer.
+type Broadcasters struct {
+	mutex        sync.Mutex
+	liveOptions  *LiveOptions
+	broadcasters map[string]*Broadcaster
+}
+
+// Broadcaster fans out the frames of a single consumer to all the subscribers of a channel.
+type Broadcaster struct {
+	path        string
+	owner       *Broadcasters
+	mutex       sync.RWMutex
+	subscribers map[*Subscriber]struct{}
+	cancel      context.CancelFunc
+	done        chan struct{}
+	err         error
+}
+
+// Subscriber sends the frames of a broadcaster to a single stream sender.
+type Subscriber struct {
+	broadcaster *Broadcaster
+	sender      *backend.StreamSender
+	policy      string
+	frames      chan []byte
+	closed      chan struct{}
+	stopOnce    sync.Once
+	closeOnce   sync.Once
+	dropped     atomic.Int64
+}
+
+func NewBroadcasters(liveOptions *LiveOptions) *Broadcasters {
+	return &Broadcasters{
+		liveOptions:  liveOptions,
+		broadcasters: make(map[string]*Broadcaster),
+	}
+}
+
+// Subscribe adds the sender to the broadcaster of the channel path, and starts the
+// consumer of the channel when it is the first subscriber.
+func (broadcasters *Broadcasters) Subscribe(path string, sender *backend.StreamSender, consume ConsumeFunc) *Subscriber {
+	broadcasters.mutex.Lock()
+	defer broadcasters.mutex.Unlock()
+
+	broadcaster, exists := broadcasters.broadcasters[path]
+	if !exists {
+		ctx, cancel := context.WithCancel(context.Background())
+		broadcaster = &Broadcaster{
+			path:        path,
+			owner:       broadcasters,
+			subscribers: make(map[*Subscriber]struct{}),
+			cancel:      cancel,
+			done:        make(chan struct{}),
+		}
+		broadcasters.broadcasters[path] = broadcaster
+		go broadcaster.run(ctx, consume)
+		log.DefaultLogger.Debug("Started new broadcaster", "path", path)
+	}
+
+	subscriber := &Subscriber{
+		broadcaster: broadcaster,
+		sender:      sender,
+		policy:      broadcasters.liveOptions.getPolicy(),
+		frames:      make(chan []byte, broadcasters.liveOptions.getBufferSize()),
+		closed:      make(chan struct{}),
+	}
+	broadcaster.mutex.Lock()
+	broadcaster.subscribers[subscriber] = struct{}{}
+	broadcaster.mutex.Unlock()
+	go subscriber.run()
+
+	return subscriber
+}
+
+func (broadcasters *Broadcasters) unsubscribe(subscriber *Subscriber) {
+	broadcasters.mutex.Lock()
+	defer broadcasters.mutex.Unlock()
+
+	broadcaster := subscriber.broadcaster
+	broadcaster.mutex.Lock()
+	delete(broadcaster.subscribers, subscriber)
+	isLastSubscriber := len(broadcaster.subscribers) == 0
+	broadcaster.mutex.Unlock()
+
+	if isLastSubscriber {
+		broadcasters.forget(broadcaster)
+		broadcaster.cancel()
+		log.DefaultLogger.Debug("Stopped broadcaster without subscribers", "path", broadcaster.path)
+	}
+}
+
+// Close stops the consumers of every channel and the sending of their subscribers, when the datasource is disposed.
+func (broadcasters *Broadcasters) Close() {
+	broadcasters.mutex.Lock()
+	closing := broadcasters.broadcasters
+	broadcasters.broadcasters = make(map[string]*Broadcaster)
+	broadcasters.mutex.Unlock()
+
+	for _, broadcaster := range closing {
+		broadcaster.cancel()
+		broadcaster.mutex.RLock()
+		for subscriber := range broadcaster.subscribers {
+			subscriber.stop()
+		}
+		broadcaster.mutex.RUnlock()
+		log.DefaultLogger.Debug("Stopped broadcaster of a disposed datasource", "path", broadcaster.path)
+	}
+}
+
+// forget must be called while holding the mutex of the broadcasters.
+func (broadcasters *Broadcasters) forget(broadcaster *Broadcaster) {
+	if broadcasters.broadcasters[broadcaster.path] == broadcaster {
+		delete(broadcasters.broadcasters, broadcaster.path)
+	}
+}
+
+func (broadcaster *Broadcaster) run(ctx context.Context, consume ConsumeFunc) {
+	err := consume(ctx, broadcaster)
+
+	broadcaster.owner.mutex.Lock()
+	broadcaster.owner.forget(broadcaster)
+	broadcaster.owner.mutex.Unlock()
+
+	broadcaster.err = err
+	close(broadcaster.done)
+}
+
+// Broadcast queues the JSON encoded frame for every subscriber without waiting for them.
+func (broadcaster *Broadcaster) Broadcast(frameJSON []byte) {
+	broadcaster.mutex.RLock()
+	defer broadcaster.mutex.RUnlock()
+	for subscriber := range broadcaster.subscribers {
+		subscriber.push(frameJSON)
+	}
+}
+
+func (subscriber *Subscriber) push(frameJSON []byte) {
+	select {
+	case subscriber.frames <- frameJSON:
+		return
+	default:
+	}
+
+	if subscriber.policy == SLOW_SUBSCRIBER_BUFFER {
+		// make room for the new frame by dropping the oldest one
+		select {
+		case <-subscriber.frames:
+		default:
+		}
+		select {
+		case subscriber.frames <- frameJSON:
+		default:
+		}
+	}
+
+	if subscriber.dropped.Add(1) == 1 {
+		log.DefaultLogger.Warn("Subscriber is too slow, dropping frames", "path", subscriber.broadcaster.path, "policy", subscriber.policy)
+	}
+}
+
+func (subscriber *Subscriber) run() {
+	for {
+		select {
+		case <-subscriber.closed:
+			return
+		case frameJSON := <-subscriber.frames:
+			if err := subscriber.sender.SendJSON(frameJSON); err != nil {
+				log.DefaultLogger.Error("Error sending frame", "path", subscriber.broadcaster.path, "error", err)
+			}
+		}
+	}
+}
+
+// Done is closed once the consumer of the channel stopped, Err returns the reason.
+func (subscriber *Subscriber) Done() <-chan struct{} {
+	return subscriber.broadcaster.done
+}
+
+func (subscriber *Subscriber) Err() error {
+	return subscriber.broadcaster.err
+}
+
+// stop ends the sending of the frames of the subscriber.
+func (subscriber *Subscriber) stop() {
+	subscriber.stopOnce.Do(func() {
+		close(subscriber.closed)
+	})
+}
+
+// Close removes the subscriber, the consumer of the channel is stopped with its last subscriber.
+func (subscriber *Subscriber) Close() {
+	subscriber.closeOnce.Do(func() {
+		subscriber.stop()
+		subscriber.broadcaster.owner.unsubscribe(subscriber)
+		if dropped := subscriber.dropped.Load(); dropped > 0 {
+			log.DefaultLogger.Warn("Subscriber closed after dropping frames", "path", subscriber.broadcaster.path, "dropped", dropped)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// fakePacketSender records the frames that were sent to a subscriber.
+type fakePacketSender struct {
+	mutex  sync.Mutex
+	frames []string
+}
+
+func (sender *fakePacketSender) Send(packet *backend.StreamPacket) error {
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+	sender.frames = append(sender.frames, string(packet.Data))
+	return nil
+}
+
+func (sender *fakePacketSender) sentFrames() []string {
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+	return append([]string{}, sender.frames...)
+}
+
+// newSlowSubscriber returns a subscriber that doesn't send its frames, like a subscriber that is still sending.
+func newSlowSubscriber(liveOptions *LiveOptions) *Subscriber {
+	return &Subscriber{
+		broadcaster: &Broadcaster{path: STREAM_CHANNEL_PATH},
+		policy:      liveOptions.getPolicy(),
+		frames:      make(chan []byte, liveOptions.getBufferSize()),
+		closed:      make(chan struct{}),
+	}
+}
+
+func TestSlowSubscriberPolicies(t *testing.T) {
+	tests := []struct {
+		name            string
+		liveOptions     *LiveOptions
+		expectedFrames  []string
+		expectedDropped int64
+	}{
+		{
+			// the frames that arrive while the subscriber is sending are dropped
+			name:            "drop",
+			liveOptions:     &LiveOptions{SlowSubscriberPolicy: SLOW_SUBSCRIBER_DROP, SubscriberBufferSize: 3},
+			expectedFrames:  []string{"1"},
+			expectedDropped: 4,
+		},
+		{
+			// the oldest frames are dropped once the buffer is full
+			name:            "buffer",
+			liveOptions:     &LiveOptions{SlowSubscriberPolicy: SLOW_SUBSCRIBER_BUFFER, SubscriberBufferSize: 3},
+			expectedFrames:  []string{"3", "4", "5"},
+			expectedDropped: 2,
+		},
+		{
+			name:            "default buffer",
+			liveOptions:     &LiveOptions{},
+			expectedFrames:  []string{"1", "2", "3", "4", "5"},
+			expectedDropped: 0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subscriber := newSlowSubscriber(test.liveOptions)
+			for _, frame := range []string{"1", "2", "3", "4", "5"} {
+				subscriber.push([]byte(frame))
+			}
+			close(subscriber.frames)
+
+			frames := []string{}
+			for frame := range subscriber.frames {
+				frames = append(frames, string(frame))
+			}
+			if !reflect.DeepEqual(frames, test.expectedFrames) {
+				t.Errorf("unexpected frames: got %v, want %v", frames, test.expectedFrames)
+			}
+			if dropped := subscriber.dropped.Load(); dropped != test.expectedDropped {
+				t.Errorf("unexpected dropped frames: got %d, want %d", dropped, test.expectedDropped)
+			}
+		})
+	}
+}
+
+func TestBroadcasterSharesTheConsumer(t *testing.T) {
+	broadcasters := NewBroadcasters(NewLiveOptions())
+	var consumers atomic.Int32
+	started := make(chan *Broadcaster, 1)
+	stopped := make(chan struct{})
+	consume := func(ctx context.Context, broadcaster *Broadcaster) error {
+		consumers.Add(1)
+		started <- broadcaster
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	}
+
+	firstSender, secondSender := &fakePacketSender{}, &fakePacketSender{}
+	first := broadcasters.Subscribe(STREAM_CHANNEL_PATH, backend.NewStreamSender(firstSender), consume)
+	second := broadcasters.Subscribe(STREAM_CHANNEL_PATH, backend.NewStreamSender(secondSender), consume)
+	broadcaster := <-started
+	broadcaster.Broadcast([]byte(`{"frame": 1}`))
+
+	waitFor(t, func() bool { return len(firstSender.sentFrames()) == 1 && len(secondSender.sentFrames()) == 1 })
+	if count := consumers.Load(); count != 1 {
+		t.Errorf("unexpected number of consumers: got %d, want 1", count)
+	}
+
+	// the consumer is stopped with the last subscriber
+	first.Close()
+	select {
+	case <-stopped:
+		t.Fatalf("the consumer was stopped while a subscriber is left")
+	default:
+	}
+	second.Close()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the consumer was not stopped after the last subscriber")
+	}
+	<-second.Done()
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("the condition was not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBroadcastersClose(t *testing.T) {
+	broadcasters := NewBroadcasters(NewLiveOptions())
+	consume := func(ctx context.Context, broadcaster *Broadcaster) error {
+		<-ctx.Done()
+		return nil
+	}
+	stream := broadcasters.Subscribe(STREAM_CHANNEL_PATH, backend.NewStreamSender(&fakePacketSender{}), consume)
+	other := broadcasters.Subscribe(STREAM_CHANNEL_PATH+"/other", backend.NewStreamSender(&fakePacketSender{}), consume)
+
+	broadcasters.Close()
+	for _, subscriber := range []*Subscriber{stream, other} {
+		select {
+		case <-subscriber.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the consumer of %s was not stopped", subscriber.broadcaster.path)
+		}
+		select {
+		case <-subscriber.closed:
+		default:
+			t.Errorf("the subscriber of %s is still sending", subscriber.broadcaster.path)
+		}
+		// the subscribers are closed afterwards by their streams
+		subscriber.Close()
+	}
+}
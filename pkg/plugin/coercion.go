@@ -0,0 +1,136 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	// FIELD_TYPE_AUTO keeps the type of the JSON value
+	FIELD_TYPE_AUTO    = "auto"
+	FIELD_TYPE_NUMBER  = "number"
+	FIELD_TYPE_STRING  = "string"
+	FIELD_TYPE_BOOLEAN = "boolean"
+	FIELD_TYPE_TIME    = "time"
+	FIELD_TYPE_JSON    = "json"
+)
+
+var fieldTypes = map[string]bool{
+	"":                 true,
+	FIELD_TYPE_AUTO:    true,
+	FIELD_TYPE_NUMBER:  true,
+	FIELD_TYPE_STRING:  true,
+	FIELD_TYPE_BOOLEAN: true,
+	FIELD_TYPE_TIME:    true,
+	FIELD_TYPE_JSON:    true,
+}
+
+// coerceValue converts a decoded JSON value into the value of a field of the given type. A time is parsed
+// from an RFC3339 string or from a number (or a numeric string) of milliseconds since the epoch.
+func coerceValue(value interface{}, fieldType string) (data.FieldType, interface{}, error) {
+	switch fieldType {
+	case FIELD_TYPE_NUMBER:
+		number, err := toNumber(value)
+		return data.FieldTypeNullableFloat64, &number, err
+	case FIELD_TYPE_STRING:
+		text, err := toString(value)
+		return data.FieldTypeNullableString, &text, err
+	case FIELD_TYPE_BOOLEAN:
+		boolean, err := toBoolean(value)
+		return data.FieldTypeNullableBool, &boolean, err
+	case FIELD_TYPE_TIME:
+		timestamp, err := toTime(value)
+		return data.FieldTypeNullableTime, &timestamp, err
+	case FIELD_TYPE_JSON:
+		raw, err := json.Marshal(value)
+		return data.FieldTypeJSON, json.RawMessage(raw), err
+	}
+
+	switch typedValue := value.(type) {
+	case string:
+		return data.FieldTypeNullableString, &typedValue, nil
+	case float64:
+		return data.FieldTypeNullableFloat64, &typedValue, nil
+	case bool:
+		return data.FieldTypeNullableBool, &typedValue, nil
+	default:
+		raw, err := json.Marshal(value)
+		return data.FieldTypeJSON, json.RawMessage(raw), err
+	}
+}
+
+// fromFieldValue returns the decoded JSON value of a value that was added to a field, so it can be converted to another type.
+func fromFieldValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case *string:
+		return *typedValue
+	case *float64:
+		return *typedValue
+	case *bool:
+		return *typedValue
+	case json.RawMessage:
+		var decodedValue interface{}
+		if err := json.Unmarshal(typedValue, &decodedValue); err != nil {
+			return string(typedValue)
+		}
+		return decodedValue
+	}
+	return value
+}
+
+func toNumber(value interface{}) (float64, error) {
+	switch typedValue := value.(type) {
+	case float64:
+		return typedValue, nil
+	case string:
+		return strconv.ParseFloat(typedValue, 64)
+	case bool:
+		if typedValue {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("can't convert %T to a number", value)
+}
+
+func toString(value interface{}) (string, error) {
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue, nil
+	case float64:
+		return strconv.FormatFloat(typedValue, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(typedValue), nil
+	}
+	raw, err := json.Marshal(value)
+	return string(raw), err
+}
+
+func toBoolean(value interface{}) (bool, error) {
+	switch typedValue := value.(type) {
+	case bool:
+		return typedValue, nil
+	case float64:
+		return typedValue != 0, nil
+	case string:
+		return strconv.ParseBool(typedValue)
+	}
+	return false, fmt.Errorf("can't convert %T to a boolean", value)
+}
+
+func toTime(value interface{}) (time.Time, error) {
+	switch typedValue := value.(type) {
+	case string:
+		if epoch, err := strconv.ParseFloat(typedValue, 64); err == nil {
+			return time.UnixMilli(int64(epoch)), nil
+		}
+		return time.Parse(time.RFC3339Nano, typedValue)
+	case float64:
+		return time.UnixMilli(int64(typedValue)), nil
+	}
+	return time.Time{}, fmt.Errorf("can't convert %T to a time", value)
+}
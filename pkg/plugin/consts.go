@@ -3,3 +3,5 @@ package plugin
 const STREAM_CHANNEL_PATH = "rabbitmq"
 const FRAME_NAME = "rabbitmq"
 const TIMESTAMP_NAME = "RmqMsgConsumedTimestamp"
+const PARTITION_NAME = "RmqMsgPartition"
+const DEFAULT_MAX_ROWS = 10000
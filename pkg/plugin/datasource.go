@@ -3,6 +3,7 @@ package plugin
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"github.com/maormil/rabbitmq-datasource/pkg/rabbitmqclient"
 
@@ -31,6 +32,11 @@ func NewRabbitMQInstance(ctx context.Context, s backend.DataSourceInstanceSettin
 		return nil, err
 	}
 
+	liveOptions, err := getLiveOptions(s)
+	if err != nil {
+		return nil, err
+	}
+
 	log.DefaultLogger.Debug("New RabbitMQ Instance Datasource settings were set!")
 
 	_, err = client.Connect()
@@ -40,16 +46,20 @@ func NewRabbitMQInstance(ctx context.Context, s backend.DataSourceInstanceSettin
 
 	log.DefaultLogger.Debug("Successfully connected to the RabbitMQ!")
 
-	return NewRabbitMQDatasource(client), nil
+	return NewRabbitMQDatasource(client, liveOptions), nil
 }
 
 type RabbitMQDatasource struct {
-	Client rabbitmqclient.Client
+	Client       rabbitmqclient.Client
+	Broadcasters *Broadcasters
+	Queries      *QueryStore
 }
 
-func NewRabbitMQDatasource(client rabbitmqclient.Client) *RabbitMQDatasource {
+func NewRabbitMQDatasource(client rabbitmqclient.Client, liveOptions *LiveOptions) *RabbitMQDatasource {
 	return &RabbitMQDatasource{
-		Client: client,
+		Client:       client,
+		Broadcasters: NewBroadcasters(liveOptions),
+		Queries:      NewQueryStore(),
 	}
 }
 
@@ -58,6 +68,7 @@ func NewRabbitMQDatasource(client rabbitmqclient.Client) *RabbitMQDatasource {
 // by SDK old datasource instance will be disposed and a new one will be created
 // using RabbitMQDatasource factory function.
 func (ds *RabbitMQDatasource) Dispose() {
+	ds.Broadcasters.Close()
 	ds.Client.Dispose()
 }
 
@@ -73,15 +84,77 @@ func getDatasourceSettings(s backend.DataSourceInstanceSettings) (*rabbitmqclien
 
 	log.DefaultLogger.Debug("Successfully unmarshelled the JSONData!")
 
+	if streamOptions := rabbitmqStreamOptions.StreamOptions; streamOptions != nil {
+		if err := streamOptions.GetOffsetOptions().Validate(); err != nil {
+			return nil, fmt.Errorf("invalid offset of the datasource stream: %w", err)
+		}
+		if streamOptions.OffsetStorage != nil {
+			if err := streamOptions.OffsetStorage.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid offset storage of the datasource stream: %w", err)
+			}
+		}
+		if err := streamOptions.Filter.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid filter of the datasource stream: %w", err)
+		}
+	}
+
+	for _, queueOptions := range rabbitmqStreamOptions.QueuesOptions {
+		if err := queueOptions.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if reconnectOptions := rabbitmqStreamOptions.ReconnectOptions; reconnectOptions != nil {
+		if err := reconnectOptions.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	if password, exists := s.DecryptedSecureJSONData["password"]; exists {
 		rabbitmqStreamOptions.Password = password
 	}
+	if oauth2ClientSecret, exists := s.DecryptedSecureJSONData["oauth2ClientSecret"]; exists {
+		rabbitmqStreamOptions.OAuth2ClientSecret = oauth2ClientSecret
+	}
+	if tlsCACert, exists := s.DecryptedSecureJSONData["tlsCACert"]; exists {
+		rabbitmqStreamOptions.TLSCACert = tlsCACert
+	}
+	if tlsClientCert, exists := s.DecryptedSecureJSONData["tlsClientCert"]; exists {
+		rabbitmqStreamOptions.TLSClientCert = tlsClientCert
+	}
+	if tlsClientKey, exists := s.DecryptedSecureJSONData["tlsClientKey"]; exists {
+		rabbitmqStreamOptions.TLSClientKey = tlsClientKey
+	}
 
 	log.DefaultLogger.Debug("Successfully decrypted secure JSONData!")
 
+	if err := rabbitmqStreamOptions.ValidateAuth(); err != nil {
+		return nil, err
+	}
+	if err := rabbitmqStreamOptions.ValidateEndpoints(); err != nil {
+		return nil, err
+	}
+
 	client.SetRabbitMQOptions(rabbitmqStreamOptions)
 
 	log.DefaultLogger.Debug("Successfully set the RabbitMQOptions in the RabbitMQStreamClient!")
 
 	return client, nil
 }
+
+func getLiveOptions(s backend.DataSourceInstanceSettings) (*LiveOptions, error) {
+	settings := struct {
+		LiveOptions *LiveOptions `json:"liveOptions"`
+	}{
+		LiveOptions: NewLiveOptions(),
+	}
+
+	if err := json.Unmarshal(s.JSONData, &settings); err != nil {
+		return nil, err
+	}
+	if settings.LiveOptions == nil {
+		return NewLiveOptions(), nil
+	}
+
+	return settings.LiveOptions, nil
+}
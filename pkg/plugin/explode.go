@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/PaesslerAG/gval"
+	"github.com/PaesslerAG/jsonpath"
+)
+
+// ExplodeOptions frames every item of an array in the message as a row of its own, for the
+// producers that batch several samples into a single message (like [{"ts": ..., "v": ...}, ...]).
+type ExplodeOptions struct {
+	// Path is a JSONPath expression that selects the array, the message itself is the array when it is empty
+	Path string `json:"path,omitempty"`
+}
+
+func (explodeOptions *ExplodeOptions) Validate() error {
+	if explodeOptions == nil || explodeOptions.Path == "" {
+		return nil
+	}
+	if _, err := jsonpath.New(explodeOptions.Path); err != nil {
+		return fmt.Errorf("invalid JSONPath expression %s of the exploded array: %w", explodeOptions.Path, err)
+	}
+	return nil
+}
+
+// arrayExploder splits a message into the items of its array.
+type arrayExploder struct {
+	path gval.Evaluable
+}
+
+// newArrayExploder compiles the path of the exploded array, it was already validated with the query.
+func newArrayExploder(explodeOptions *ExplodeOptions) *arrayExploder {
+	if explodeOptions == nil {
+		return nil
+	}
+	exploder := &arrayExploder{}
+	if explodeOptions.Path != "" {
+		exploder.path, _ = jsonpath.New(explodeOptions.Path)
+	}
+	return exploder
+}
+
+// explode returns the items of the array as the rows of the message. A message without the
+// array is a single row, and a message with an empty array has no rows.
+func (exploder *arrayExploder) explode(value []byte) ([][]byte, error) {
+	if exploder == nil {
+		return [][]byte{value}, nil
+	}
+	if exploder.path == nil {
+		var items []json.RawMessage
+		if err := json.Unmarshal(value, &items); err != nil {
+			return [][]byte{value}, nil
+		}
+		rows := make([][]byte, len(items))
+		for index, item := range items {
+			rows[index] = item
+		}
+		return rows, nil
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(value, &document); err != nil {
+		return nil, err
+	}
+	selected, err := exploder.path(context.Background(), document)
+	items, isArray := selected.([]interface{})
+	if err != nil || !isArray {
+		return [][]byte{value}, nil
+	}
+	rows := make([][]byte, 0, len(items))
+	for _, item := range items {
+		row, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExplode(t *testing.T) {
+	tests := []struct {
+		name           string
+		explodeOptions *ExplodeOptions
+		message        string
+		expectedRows   []string
+		expectError    bool
+	}{
+		{
+			name:         "no explode",
+			message:      `[{"v": 1}, {"v": 2}]`,
+			expectedRows: []string{`[{"v": 1}, {"v": 2}]`},
+		},
+		{
+			name:           "message array",
+			explodeOptions: &ExplodeOptions{},
+			message:        `[{"v": 1}, {"v": 2}]`,
+			expectedRows:   []string{`{"v": 1}`, `{"v": 2}`},
+		},
+		{
+			name:           "message that is not an array",
+			explodeOptions: &ExplodeOptions{},
+			message:        `{"v": 1}`,
+			expectedRows:   []string{`{"v": 1}`},
+		},
+		{
+			name:           "array of the path",
+			explodeOptions: &ExplodeOptions{Path: "$.samples"},
+			message:        `{"device": "boiler", "samples": [{"v": 1}, 2]}`,
+			expectedRows:   []string{`{"v":1}`, `2`},
+		},
+		{
+			name:           "message without the array",
+			explodeOptions: &ExplodeOptions{Path: "$.samples"},
+			message:        `{"device": "boiler"}`,
+			expectedRows:   []string{`{"device": "boiler"}`},
+		},
+		{
+			name:           "empty array",
+			explodeOptions: &ExplodeOptions{Path: "$.samples"},
+			message:        `{"samples": []}`,
+			expectedRows:   []string{},
+		},
+		{
+			name:           "invalid message",
+			explodeOptions: &ExplodeOptions{Path: "$.samples"},
+			message:        `{"samples": [`,
+			expectError:    true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.explodeOptions.Validate(); err != nil {
+				t.Fatalf("invalid explode options: %v", err)
+			}
+			rows, err := newArrayExploder(test.explodeOptions).explode([]byte(test.message))
+			if (err != nil) != test.expectError {
+				t.Fatalf("unexpected error: got %v, want an error: %v", err, test.expectError)
+			}
+			if test.expectError {
+				return
+			}
+			actualRows := make([]string, len(rows))
+			for index, row := range rows {
+				actualRows[index] = string(row)
+			}
+			if !reflect.DeepEqual(actualRows, test.expectedRows) {
+				t.Errorf("unexpected rows: got %v, want %v", actualRows, test.expectedRows)
+			}
+		})
+	}
+}
+
+func TestExplodeFrame(t *testing.T) {
+	framer := NewFramer(&FramerOptions{Explode: &ExplodeOptions{Path: "$.samples"}})
+	for _, message := range []string{`{"samples": [{"v": 1}, {"v": 2}]}`, `{"samples": [{"v": 3}]}`} {
+		if err := framer.AppendMessage(NewTimestampedMessage([]byte(message))); err != nil {
+			t.Fatalf("AppendMessage returned an error: %v", err)
+		}
+	}
+
+	field, _ := framer.Frame().FieldByName("v")
+	if field == nil {
+		t.Fatalf("the v field is missing")
+	}
+	values := make([]interface{}, field.Len())
+	for index := range values {
+		values[index], _ = field.ConcreteAt(index)
+	}
+	expectedValues := []interface{}{float64(1), float64(2), float64(3)}
+	if !reflect.DeepEqual(values, expectedValues) {
+		t.Errorf("unexpected values: got %v, want %v", values, expectedValues)
+	}
+}
+
+func TestValidateExplode(t *testing.T) {
+	if err := (&ExplodeOptions{Path: "$.samples["}).Validate(); err == nil {
+		t.Errorf("the invalid path was accepted")
+	}
+}
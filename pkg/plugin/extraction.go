@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PaesslerAG/jsonpath"
+	"github.com/jmespath/go-jmespath"
+)
+
+const (
+	EXTRACTION_LANGUAGE_JSONPATH = "jsonpath"
+	EXTRACTION_LANGUAGE_JMESPATH = "jmespath"
+)
+
+// Extraction is an expression that extracts a single field out of the messages, like "$.payload.temperature"
+// in JSONPath or "payload.temperature" in JMESPath.
+type Extraction struct {
+	Expression string `json:"expression"`
+	// Language is jsonpath (the default) or jmespath
+	Language string `json:"language,omitempty"`
+	// Alias is the name of the field, the expression is the name when it is empty
+	Alias string `json:"alias,omitempty"`
+	// Type converts the extracted value, it keeps the type of the JSON value when it is empty
+	Type string `json:"type,omitempty"`
+}
+
+// extractor evaluates a compiled extraction expression on a decoded message.
+type extractor struct {
+	name       string
+	fieldType  string
+	evaluate   func(document interface{}) (interface{}, error)
+	expression string
+}
+
+func (extraction *Extraction) Validate() error {
+	if extraction.Expression == "" {
+		return fmt.Errorf("invalid extraction: the expression must not be empty")
+	}
+	if !fieldTypes[extraction.Type] {
+		return fmt.Errorf("invalid extraction %s: unknown type %s", extraction.Expression, extraction.Type)
+	}
+	if _, err := extraction.compile(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (extraction *Extraction) getName() string {
+	if extraction.Alias == "" {
+		return extraction.Expression
+	}
+	return extraction.Alias
+}
+
+func (extraction *Extraction) compile() (*extractor, error) {
+	extractor := &extractor{
+		name:       extraction.getName(),
+		fieldType:  extraction.Type,
+		expression: extraction.Expression,
+	}
+	switch extraction.Language {
+	case "", EXTRACTION_LANGUAGE_JSONPATH:
+		evaluable, err := jsonpath.New(extraction.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSONPath expression %s: %w", extraction.Expression, err)
+		}
+		extractor.evaluate = func(document interface{}) (interface{}, error) {
+			return evaluable(context.Background(), document)
+		}
+	case EXTRACTION_LANGUAGE_JMESPATH:
+		compiled, err := jmespath.Compile(extraction.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JMESPath expression %s: %w", extraction.Expression, err)
+		}
+		extractor.evaluate = compiled.Search
+	default:
+		return nil, fmt.Errorf("invalid extraction %s: unknown language %s, the language must be %s or %s",
+			extraction.Expression, extraction.Language, EXTRACTION_LANGUAGE_JSONPATH, EXTRACTION_LANGUAGE_JMESPATH)
+	}
+	return extractor, nil
+}
+
+// compileExtractions compiles the extractions of the query, they were already validated with the query.
+func compileExtractions(extractions []*Extraction) []*extractor {
+	extractors := make([]*extractor, 0, len(extractions))
+	for _, extraction := range extractions {
+		if extractor, err := extraction.compile(); err == nil {
+			extractors = append(extractors, extractor)
+		}
+	}
+	return extractors
+}
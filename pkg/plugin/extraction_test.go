@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+const extractionMessage = `{"payload": {"temperature": "21.5", "readings": [{"value": 1}, {"value": 3}]}, "device": "boiler"}`
+
+func TestExtractions(t *testing.T) {
+	tests := []struct {
+		name             string
+		extraction       *Extraction
+		expectedRow      map[string]interface{}
+		expectedRejected int64
+	}{
+		{
+			name:        "JSONPath",
+			extraction:  &Extraction{Expression: "$.device"},
+			expectedRow: map[string]interface{}{"$.device": "boiler"},
+		},
+		{
+			name:        "JSONPath with an alias and a type",
+			extraction:  &Extraction{Expression: "$.payload.temperature", Alias: "temperature", Type: FIELD_TYPE_NUMBER},
+			expectedRow: map[string]interface{}{"temperature": 21.5},
+		},
+		{
+			name:        "JSONPath array as JSON",
+			extraction:  &Extraction{Expression: "$.payload.readings[*].value", Alias: "values"},
+			expectedRow: map[string]interface{}{"values": "[1,3]"},
+		},
+		{
+			name:        "JMESPath",
+			extraction:  &Extraction{Expression: "payload.temperature", Language: EXTRACTION_LANGUAGE_JMESPATH, Alias: "temperature"},
+			expectedRow: map[string]interface{}{"temperature": "21.5"},
+		},
+		{
+			name:        "JMESPath function",
+			extraction:  &Extraction{Expression: "max(payload.readings[].value)", Language: EXTRACTION_LANGUAGE_JMESPATH, Alias: "max"},
+			expectedRow: map[string]interface{}{"max": float64(3)},
+		},
+		{
+			name:        "missing value",
+			extraction:  &Extraction{Expression: "payload.humidity", Language: EXTRACTION_LANGUAGE_JMESPATH, Alias: "humidity"},
+			expectedRow: map[string]interface{}{},
+		},
+		{
+			name:             "value of another type",
+			extraction:       &Extraction{Expression: "$.device", Alias: "device", Type: FIELD_TYPE_NUMBER},
+			expectedRow:      map[string]interface{}{},
+			expectedRejected: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.extraction.Validate(); err != nil {
+				t.Fatalf("invalid extraction: %v", err)
+			}
+			framer := NewFramer(&FramerOptions{Extractions: []*Extraction{test.extraction}})
+			row := frameFirstRow(t, framer, extractionMessage)
+			if !reflect.DeepEqual(row, test.expectedRow) {
+				t.Errorf("unexpected row: got %v, want %v", row, test.expectedRow)
+			}
+			if rejected := framer.getRejectedValues()[test.extraction.getName()]; rejected != test.expectedRejected {
+				t.Errorf("unexpected rejected values: got %d, want %d", rejected, test.expectedRejected)
+			}
+		})
+	}
+}
+
+func TestValidateExtraction(t *testing.T) {
+	tests := []struct {
+		name       string
+		extraction *Extraction
+	}{
+		{name: "empty expression", extraction: &Extraction{}},
+		{name: "invalid JSONPath", extraction: &Extraction{Expression: "$.payload["}},
+		{name: "invalid JMESPath", extraction: &Extraction{Expression: "payload.[", Language: EXTRACTION_LANGUAGE_JMESPATH}},
+		{name: "unknown language", extraction: &Extraction{Expression: "payload", Language: "xpath"}},
+		{name: "unknown type", extraction: &Extraction{Expression: "$.payload", Type: "integer"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.extraction.Validate(); err == nil {
+				t.Errorf("the invalid extraction was accepted")
+			}
+		})
+	}
+}
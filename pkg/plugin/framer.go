@@ -3,8 +3,11 @@ package plugin
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	jsoniter "github.com/json-iterator/go"
@@ -15,16 +18,43 @@ type Framer struct {
 	Iterator *jsoniter.Iterator
 	Fields   []*data.Field
 	FieldMap map[string]int
+	Options  *FramerOptions
+	// extractors frame the extracted fields of the options instead of the keys of the messages
+	extractors []*extractor
+	// exploder splits the messages into rows when an array of the messages is exploded
+	exploder *arrayExploder
+	// timestamper picks the time of the rows, the time the message was consumed when it is nil
+	timestamper *timestamper
+	// schema holds the declared schema fields by their names
+	schema            map[string]*SchemaField
+	rejectedValues    map[string]int64
+	pendingRejections map[string]int64
 }
 
-func NewFramer() *Framer {
+func NewFramer(options *FramerOptions) *Framer {
+	if options == nil {
+		options = &FramerOptions{}
+	}
 	df := &Framer{
-		FieldMap: make(map[string]int),
+		FieldMap:    make(map[string]int),
+		Options:     options,
+		extractors:  compileExtractions(options.Extractions),
+		exploder:    newArrayExploder(options.Explode),
+		timestamper: newTimestamper(options.Timestamp),
+
+		schema:            make(map[string]*SchemaField, len(options.Schema)),
+		rejectedValues:    make(map[string]int64),
+		pendingRejections: make(map[string]int64),
 	}
 	timeField := data.NewFieldFromFieldType(data.FieldTypeTime, 0)
 	timeField.Name = TIMESTAMP_NAME
 	df.Fields = append(df.Fields, timeField)
 	df.FieldMap[TIMESTAMP_NAME] = 0
+	for _, schemaField := range options.Schema {
+		df.schema[schemaField.Name] = schemaField
+		df.Fields = append(df.Fields, schemaField.newField())
+		df.FieldMap[schemaField.Name] = len(df.Fields) - 1
+	}
 	return df
 }
 
@@ -45,45 +75,75 @@ func (df *Framer) Next() error {
 	case jsoniter.ArrayValue:
 		df.AddValue(data.FieldTypeJSON, json.RawMessage(df.Iterator.SkipAndReturnBytes()))
 	case jsoniter.ObjectValue:
-		size := len(df.Path)
-		if size > 0 {
+		if !df.Options.Flatten.shouldFlatten(df.Path, df.Key()) {
 			df.AddValue(data.FieldTypeJSON, json.RawMessage(df.Iterator.SkipAndReturnBytes()))
 			break
 		}
 		for fname := df.Iterator.ReadObject(); fname != ""; fname = df.Iterator.ReadObject() {
-			if size == 0 {
-				df.Path = append(df.Path, fname)
-				if err := df.Next(); err != nil {
-					return err
-				}
+			df.Path = append(df.Path, fname)
+			if err := df.Next(); err != nil {
+				return err
 			}
+			df.Path = df.Path[:len(df.Path)-1]
 		}
 	case jsoniter.InvalidValue:
 		return fmt.Errorf("invalid value")
 	}
+	return nil
+}
+
+// Extract adds the values of the extraction expressions, every expression is a field named by its alias.
+// A value that is missing or that can't be converted to the type of the extraction is left empty.
+func (df *Framer) Extract(value []byte) error {
+	var document interface{}
+	if err := json.Unmarshal(value, &document); err != nil {
+		return err
+	}
+	for _, extractor := range df.extractors {
+		df.Path = []string{extractor.name}
+		extractedValue, err := extractor.evaluate(document)
+		if err != nil || extractedValue == nil {
+			df.AddNil()
+			continue
+		}
+		fieldType, fieldValue, err := coerceValue(extractedValue, extractor.fieldType)
+		if err != nil {
+			log.DefaultLogger.Debug("Failed to convert the extracted value", "expression", extractor.expression, "type", extractor.fieldType, "error", err)
+			df.rejectValue(extractor.name)
+			df.AddNil()
+			continue
+		}
+		df.AddValue(fieldType, fieldValue)
+	}
 	df.Path = []string{}
 	return nil
 }
 
+// Key is the field name of the current path, its keys are joined with the separator so "a.bc" and "ab.c" don't collide.
 func (df *Framer) Key() string {
 	if len(df.Path) == 0 {
 		return "Value"
 	}
-	return strings.Join(df.Path, "")
+	return strings.Join(df.Path, df.Options.Flatten.getSeparator())
 }
 
+// AddNil leaves the value of the current row empty, ExtendFields fills it with nil.
 func (df *Framer) AddNil() {
-	if idx, ok := df.FieldMap[df.Key()]; ok {
-		df.Fields[idx].Set(0, nil)
+	if _, ok := df.FieldMap[df.Key()]; ok {
 		return
 	}
 	log.DefaultLogger.Debug("Nil value for unknown field", "key", df.Key())
 }
 
 func (df *Framer) AddValue(fieldType data.FieldType, v interface{}) {
+	if schemaField, ok := df.schema[df.Key()]; ok {
+		df.addSchemaValue(schemaField, v)
+		return
+	}
 	if idx, ok := df.FieldMap[df.Key()]; ok {
 		if df.Fields[idx].Type() != fieldType {
 			log.DefaultLogger.Debug("Field type mismatch", "key", df.Key(), "existing", df.Fields[idx], "new", fieldType)
+			df.rejectValue(df.Key())
 			return
 		}
 		df.Fields[idx].Append(v)
@@ -97,22 +157,113 @@ func (df *Framer) AddValue(fieldType data.FieldType, v interface{}) {
 }
 
 func (df *Framer) ToFrame(message *TimestampedMessage) (*data.Frame, error) {
-	// clear the data in the fields
-	for _, field := range df.Fields {
-		for i := 0; i < field.Len(); i++ {
-			field.Delete(i)
-		}
+	df.Clear()
+	if err := df.AppendMessage(message); err != nil {
+		return nil, err
+	}
+	return df.Frame(), nil
+}
+
+// AppendMessage adds the message as a new row to the fields of the framer, or a row for
+// every item of its array when the array is exploded.
+func (df *Framer) AppendMessage(message *TimestampedMessage) error {
+	rows, err := df.exploder.explode(message.Value)
+	if err != nil {
+		log.DefaultLogger.Debug("Error exploding message", "error", err)
+		rows = [][]byte{message.Value}
+	}
+	for _, row := range rows {
+		df.appendRow(message, row)
 	}
+	return nil
+}
 
-	df.Iterator = jsoniter.ParseBytes(jsoniter.ConfigDefault, message.Value)
-	err := df.Next()
+func (df *Framer) appendRow(message *TimestampedMessage, value []byte) {
+	df.Path = []string{}
+	var err error
+	if len(df.extractors) > 0 {
+		err = df.Extract(value)
+	} else {
+		df.Iterator = jsoniter.ParseBytes(jsoniter.ConfigDefault, value)
+		err = df.Next()
+	}
 	if err != nil {
 		log.DefaultLogger.Debug("Error parsing message", "error", err)
 	}
-	df.Fields[0].Append(message.Timestamp)
+	if message.Partition != "" {
+		df.Path = []string{PARTITION_NAME}
+		df.AddValue(data.FieldTypeNullableString, &message.Partition)
+		df.Path = []string{}
+	}
+	df.fillSchemaFields()
+	timestamp, err := df.timestamper.timestamp(message, value)
+	if err != nil {
+		log.DefaultLogger.Debug("Error parsing the timestamp of the message, using the consume time", "error", err)
+	}
+	df.Fields[0].Append(timestamp)
 	df.ExtendFields(df.Fields[0].Len() - 1)
+}
+
+// Frame returns the rows of the framer, with the number of the rejected values of every field in its custom meta,
+// and a notice about the values that were rejected since the last frame.
+func (df *Framer) Frame() *data.Frame {
+	frame := data.NewFrame(FRAME_NAME, df.Fields...)
+	if len(df.rejectedValues) > 0 {
+		frame.SetMeta(&data.FrameMeta{Custom: map[string]interface{}{"rejectedValues": df.getRejectedValues()}})
+	}
+	if notice := df.getRejectionsNotice(); notice != nil {
+		frame.AppendNotices(*notice)
+		df.pendingRejections = make(map[string]int64)
+	}
+	return frame
+}
 
-	return data.NewFrame(FRAME_NAME, df.Fields...), nil
+// SortByTime sorts the rows by their timestamp, for rows that were appended from several partitions.
+func (df *Framer) SortByTime() {
+	rows := make([]int, df.Rows())
+	for row := range rows {
+		rows[row] = row
+	}
+	timeField := df.Fields[0]
+	sort.SliceStable(rows, func(i, j int) bool {
+		return timeField.At(rows[i]).(time.Time).Before(timeField.At(rows[j]).(time.Time))
+	})
+	for fieldIndex, field := range df.Fields {
+		sortedField := data.NewFieldFromFieldType(field.Type(), len(rows))
+		sortedField.Name = field.Name
+		sortedField.Config = field.Config
+		for row, sourceRow := range rows {
+			sortedField.Set(row, field.At(sourceRow))
+		}
+		df.Fields[fieldIndex] = sortedField
+	}
+}
+
+// DropRowsOutside removes the rows, from the given row on, whose time is outside of the time range.
+func (df *Framer) DropRowsOutside(timeRange backend.TimeRange, fromRow int) {
+	timeField := df.Fields[0]
+	for row := timeField.Len() - 1; row >= fromRow; row -= 1 {
+		timestamp := timeField.At(row).(time.Time)
+		if !timestamp.Before(timeRange.From) && !timestamp.After(timeRange.To) {
+			continue
+		}
+		for _, field := range df.Fields {
+			field.Delete(row)
+		}
+	}
+}
+
+func (df *Framer) Rows() int {
+	return df.Fields[0].Len()
+}
+
+// Clear removes all the rows but keeps the fields, so the next frames keep the same schema.
+func (df *Framer) Clear() {
+	for _, field := range df.Fields {
+		for field.Len() > 0 {
+			field.Delete(field.Len() - 1)
+		}
+	}
 }
 
 func (df *Framer) ExtendFields(idx int) {
@@ -0,0 +1,95 @@
+package plugin
+
+import "fmt"
+
+const DEFAULT_FLATTEN_SEPARATOR = "."
+
+// FramerOptions shapes the messages of a query into the fields of its frames.
+type FramerOptions struct {
+	// Flatten turns the nested objects into fields, otherwise only the top level keys are fields
+	Flatten *FlattenOptions `json:"flatten,omitempty"`
+	// Extractions only frames the extracted fields instead of the keys of the messages
+	Extractions []*Extraction `json:"extractions,omitempty"`
+	// Explode frames every item of an array in the messages as a row
+	Explode *ExplodeOptions `json:"explode,omitempty"`
+	// Schema declares the types of some of the fields, the other fields take the type of their first value
+	Schema []*SchemaField `json:"schema,omitempty"`
+	// Timestamp takes the time of the rows from the messages instead of the time they were consumed
+	Timestamp *TimestampOptions `json:"timestamp,omitempty"`
+}
+
+// FlattenOptions flattens the nested objects of the messages into fields, which are named by
+// the keys of their path joined with the separator (like "sensor.temperature.value").
+type FlattenOptions struct {
+	Separator string `json:"separator,omitempty"`
+	// MaxDepth is the max number of keys in a field name, the deeper objects are kept as JSON (0 for no limit)
+	MaxDepth int `json:"maxDepth,omitempty"`
+	// KeepJSON are the field names of the objects that are kept as JSON instead of being flattened
+	KeepJSON []string `json:"keepJson,omitempty"`
+}
+
+func (framerOptions *FramerOptions) Validate() error {
+	if err := framerOptions.Flatten.Validate(); err != nil {
+		return err
+	}
+	if err := framerOptions.Explode.Validate(); err != nil {
+		return err
+	}
+	if err := framerOptions.Timestamp.Validate(); err != nil {
+		return err
+	}
+	names := make(map[string]bool, len(framerOptions.Extractions))
+	for _, extraction := range framerOptions.Extractions {
+		if err := extraction.Validate(); err != nil {
+			return err
+		}
+		if names[extraction.getName()] {
+			return fmt.Errorf("invalid extraction %s: the field %s is extracted twice", extraction.Expression, extraction.getName())
+		}
+		names[extraction.getName()] = true
+	}
+	schemaNames := make(map[string]bool, len(framerOptions.Schema))
+	for _, schemaField := range framerOptions.Schema {
+		if err := schemaField.Validate(); err != nil {
+			return err
+		}
+		if schemaNames[schemaField.Name] {
+			return fmt.Errorf("invalid schema field %s: the field is declared twice", schemaField.Name)
+		}
+		schemaNames[schemaField.Name] = true
+	}
+	return nil
+}
+
+func (flattenOptions *FlattenOptions) Validate() error {
+	if flattenOptions == nil {
+		return nil
+	}
+	if flattenOptions.MaxDepth < 0 {
+		return fmt.Errorf("invalid flatten options: the max depth must not be negative")
+	}
+	return nil
+}
+
+func (flattenOptions *FlattenOptions) getSeparator() string {
+	if flattenOptions == nil || flattenOptions.Separator == "" {
+		return DEFAULT_FLATTEN_SEPARATOR
+	}
+	return flattenOptions.Separator
+}
+
+// shouldFlatten tells whether the object with the given path is flattened into fields, or kept as JSON.
+func (flattenOptions *FlattenOptions) shouldFlatten(path []string, key string) bool {
+	if len(path) == 0 {
+		return true
+	}
+	if flattenOptions == nil || (flattenOptions.MaxDepth > 0 && len(path) >= flattenOptions.MaxDepth) {
+		return false
+	}
+	for _, keptKey := range flattenOptions.KeepJSON {
+		if keptKey == key {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const flattenMessage = `{"sensor": {"name": "boiler", "temperature": {"value": 21.5, "unit": "celsius"}}, "tags": ["a", "b"], "ok": true}`
+
+// frameFirstRow returns the values of the first row by their field names, without the timestamp,
+// the JSON values are returned as strings.
+func frameFirstRow(t *testing.T, framer *Framer, message string) map[string]interface{} {
+	t.Helper()
+	frame, err := framer.ToFrame(NewTimestampedMessage([]byte(message)))
+	if err != nil {
+		t.Fatalf("ToFrame returned an error: %v", err)
+	}
+	row := make(map[string]interface{}, len(frame.Fields)-1)
+	for _, field := range frame.Fields[1:] {
+		value, _ := field.ConcreteAt(0)
+		if raw, isJSON := value.(json.RawMessage); isJSON {
+			value = string(raw)
+		}
+		row[field.Name] = value
+	}
+	return row
+}
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name        string
+		flatten     *FlattenOptions
+		expectedRow map[string]interface{}
+	}{
+		{
+			name:    "top level keys only",
+			flatten: nil,
+			expectedRow: map[string]interface{}{
+				"sensor": `{"name": "boiler", "temperature": {"value": 21.5, "unit": "celsius"}}`,
+				"tags":   `["a", "b"]`,
+				"ok":     true,
+			},
+		},
+		{
+			name:    "default separator",
+			flatten: &FlattenOptions{},
+			expectedRow: map[string]interface{}{
+				"sensor.name":              "boiler",
+				"sensor.temperature.value": 21.5,
+				"sensor.temperature.unit":  "celsius",
+				"tags":                     `["a", "b"]`,
+				"ok":                       true,
+			},
+		},
+		{
+			name:    "custom separator",
+			flatten: &FlattenOptions{Separator: "_"},
+			expectedRow: map[string]interface{}{
+				"sensor_name":              "boiler",
+				"sensor_temperature_value": 21.5,
+				"sensor_temperature_unit":  "celsius",
+				"tags":                     `["a", "b"]`,
+				"ok":                       true,
+			},
+		},
+		{
+			name:    "max depth",
+			flatten: &FlattenOptions{MaxDepth: 2},
+			expectedRow: map[string]interface{}{
+				"sensor.name":        "boiler",
+				"sensor.temperature": `{"value": 21.5, "unit": "celsius"}`,
+				"tags":               `["a", "b"]`,
+				"ok":                 true,
+			},
+		},
+		{
+			name:    "keep JSON",
+			flatten: &FlattenOptions{KeepJSON: []string{"sensor.temperature"}},
+			expectedRow: map[string]interface{}{
+				"sensor.name":        "boiler",
+				"sensor.temperature": `{"value": 21.5, "unit": "celsius"}`,
+				"tags":               `["a", "b"]`,
+				"ok":                 true,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			framer := NewFramer(&FramerOptions{Flatten: test.flatten})
+			row := frameFirstRow(t, framer, flattenMessage)
+			if !reflect.DeepEqual(row, test.expectedRow) {
+				t.Errorf("unexpected row: got %v, want %v", row, test.expectedRow)
+			}
+		})
+	}
+}
+
+func TestFlattenSeparatorCollision(t *testing.T) {
+	// the keys are joined with the separator, so "a.bc" and "ab.c" are different fields
+	framer := NewFramer(&FramerOptions{Flatten: &FlattenOptions{}})
+	row := frameFirstRow(t, framer, `{"a": {"bc": 1}, "ab": {"c": 2}}`)
+	expectedRow := map[string]interface{}{"a.bc": float64(1), "ab.c": float64(2)}
+	if !reflect.DeepEqual(row, expectedRow) {
+		t.Errorf("unexpected row: got %v, want %v", row, expectedRow)
+	}
+}
+
+func TestValidateFlatten(t *testing.T) {
+	if err := (&FlattenOptions{MaxDepth: -1}).Validate(); err == nil {
+		t.Errorf("a negative max depth was accepted")
+	}
+	if err := (&FlattenOptions{MaxDepth: 3, Separator: "/"}).Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+}
@@ -2,11 +2,21 @@ package plugin
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/maormil/rabbitmq-datasource/pkg/rabbitmqclient"
 )
 
 func (ds *RabbitMQDatasource) CheckHealth(_ context.Context, _ *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+	if message := getConnectionStateMessage(ds.Client.GetConnectionState()); message != "" {
+		return &backend.CheckHealthResult{
+			Status:  backend.HealthStatusError,
+			Message: message,
+		}, nil
+	}
+
 	if !ds.Client.IsConnected() {
 		return &backend.CheckHealthResult{
 			Status:  backend.HealthStatusError,
@@ -14,8 +24,46 @@ func (ds *RabbitMQDatasource) CheckHealth(_ context.Context, _ *backend.CheckHea
 		}, nil
 	}
 
+	// the dry run only reports the missing objects of the topology, they are created when the datasource connects again
+	diff, err := ds.Client.ReconcileTopology(true)
+	if err != nil {
+		return &backend.CheckHealthResult{
+			Status:  backend.HealthStatusError,
+			Message: fmt.Sprintf("RabbitMQ Connected, but failed to check the topology: %s", err),
+		}, nil
+	}
+	details, err := json.Marshal(diff)
+	if err != nil {
+		return nil, err
+	}
+	if diff.HasChanges() {
+		return &backend.CheckHealthResult{
+			Status:      backend.HealthStatusError,
+			Message:     fmt.Sprintf("RabbitMQ Connected, but the topology is out of sync: %s", diff),
+			JSONDetails: details,
+		}, nil
+	}
+
 	return &backend.CheckHealthResult{
-		Status:  backend.HealthStatusOk,
-		Message: "RabbitMQ Connected",
+		Status:      backend.HealthStatusOk,
+		Message:     "RabbitMQ Connected",
+		JSONDetails: details,
 	}, nil
 }
+
+// getConnectionStateMessage describes a reconnection that is still running or that gave up,
+// and returns an empty message for any other state.
+func getConnectionStateMessage(state rabbitmqclient.ConnectionState) string {
+	switch state.Status {
+	case rabbitmqclient.CONNECTION_STATUS_RECONNECTING:
+		message := fmt.Sprintf("RabbitMQ Reconnecting (attempt %d, since %s)", state.Attempt, state.Since.Format("15:04:05"))
+		if state.LastError != nil {
+			message += fmt.Sprintf(": %s", state.LastError)
+		}
+		return message
+	case rabbitmqclient.CONNECTION_STATUS_FAILED:
+		return fmt.Sprintf("RabbitMQ Reconnection Failed after %d attempts: %s", state.Attempt, state.LastError)
+	default:
+		return ""
+	}
+}
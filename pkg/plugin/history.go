@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maormil/rabbitmq-datasource/pkg/rabbitmqclient"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+// historyRead is the outcome of the read of a stream, or of a partition of a super stream.
+type historyRead struct {
+	// isTruncated is set when the history was truncated to the max rows
+	isTruncated bool
+	// undatedMessages counts the messages that were skipped, since they have no time to place them in the time range
+	undatedMessages int
+}
+
+// queryHistory reads the messages that were stored in the stream during the time range of the query.
+// The stream is read from the first chunk of the time range, until the chunk of the end of the time
+// range, the end of the stream or the max rows of the query. The read is bounded by the creation time
+// of the messages, and the rows are kept when their own time, from the timestamp options of the query,
+// is in the time range. The messages without a creation time are skipped and reported by a notice,
+// unless the rows take their time from a field of the payload. The partitions of a super stream are
+// read one after the other, and their rows are sorted by time, like the rows that take their time from the messages.
+// The queues have no history, so their queries start empty.
+func (ds *RabbitMQDatasource) queryHistory(ctx context.Context, query *RabbitMQQuery, timeRange backend.TimeRange) (*data.Frame, error) {
+	framer := NewFramer(&query.FramerOptions)
+	if query.Queue.IsEnabled() {
+		return framer.Frame(), nil
+	}
+	streamOptions, err := query.ToStreamOptions(ds.Client.GetStreamOptions())
+	if err != nil {
+		return nil, err
+	}
+	maxRows := query.getMaxRows()
+	read := &historyRead{}
+
+	log.DefaultLogger.Debug("Reading stream history", "StreamName", streamOptions.StreamName, "from", timeRange.From, "to", timeRange.To)
+
+	partitions, err := ds.Client.Partitions(streamOptions)
+	if err != nil {
+		return nil, err
+	}
+	for _, partition := range partitions {
+		partitionOptions := streamOptions
+		partitionName := ""
+		if streamOptions.SuperStream {
+			partitionOptions = streamOptions.PartitionOptions(partition)
+			partitionName = partition
+		}
+		if err = ds.readHistory(ctx, partitionOptions, partitionName, timeRange, maxRows, framer, read); err != nil {
+			return nil, err
+		}
+		if read.isTruncated {
+			break
+		}
+	}
+	if streamOptions.SuperStream || !query.Timestamp.isConsumeTime() {
+		framer.SortByTime()
+	}
+
+	frame := framer.Frame()
+	if read.isTruncated {
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("The history of the stream was truncated to %d rows", maxRows),
+		})
+	}
+	if read.undatedMessages > 0 {
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text: fmt.Sprintf("%d messages of the history were skipped, since they were published without the AMQP creation-time property "+
+				"that places them in the time range", read.undatedMessages),
+		})
+	}
+
+	log.DefaultLogger.Debug("Finished reading stream history", "StreamName", streamOptions.StreamName, "rows", framer.Rows(), "undated", read.undatedMessages)
+
+	return frame, nil
+}
+
+// readHistory appends the messages of a stream, or of a partition of a super stream, to the framer. The creation time of
+// the messages, or the offset of the chunk of the end of the time range, bounds the read, and only the rows whose time
+// is in the time range are kept. The messages without a creation time are skipped, unless the rows take their time from
+// a field of the payload.
+func (ds *RabbitMQDatasource) readHistory(ctx context.Context, streamOptions *rabbitmqclient.StreamOptions, partition string, timeRange backend.TimeRange, maxRows int, framer *Framer, read *historyRead) error {
+	reader := &historyReader{
+		framer:      framer,
+		partition:   partition,
+		timeRange:   timeRange,
+		maxRows:     maxRows,
+		endOffset:   -1,
+		read:        read,
+		isFieldTime: framer.Options.Timestamp.isFieldTime(),
+	}
+	if timeRange.To.Before(time.Now()) {
+		var err error
+		if reader.endOffset, err = ds.Client.OffsetAt(ctx, streamOptions, timeRange.To); err != nil {
+			return err
+		}
+	}
+
+	offset := stream.OffsetSpecification{}.Timestamp(timeRange.From.UnixMilli())
+	return ds.Client.Read(ctx, streamOptions, offset, func(consumerContext stream.ConsumerContext, message *amqp.Message) bool {
+		return reader.readMessage(message, consumerContext.Consumer.GetOffset)
+	})
+}
+
+// historyReader appends the messages of the read of a stream to the framer, it stops the read at the end of the time range or at the max rows.
+type historyReader struct {
+	framer    *Framer
+	partition string
+	timeRange backend.TimeRange
+	maxRows   int
+	// endOffset is the offset of the chunk of the end of the time range, or -1 when the time range ends in the future
+	endOffset int64
+	read      *historyRead
+	// isFieldTime keeps the messages without a creation time, since the rows take their time from a field of the payload
+	isFieldTime bool
+}
+
+// readMessage appends the message to the framer and returns false to stop the read. The offset
+// of the message is only needed for the messages without a creation time.
+func (reader *historyReader) readMessage(message *amqp.Message, getOffset func() int64) bool {
+	if len(message.Data) == 0 {
+		return true
+	}
+	creationTime, ok := getCreationTime(message)
+	if !ok {
+		if reader.endOffset >= 0 && getOffset() >= reader.endOffset {
+			return false
+		}
+		if !reader.isFieldTime {
+			reader.read.undatedMessages += 1
+			return true
+		}
+	} else if creationTime.After(reader.timeRange.To) {
+		return false
+	}
+	// the offset points to a whole chunk, which can start before the time range, so the rows out of it are dropped
+	firstRow := reader.framer.Rows()
+	if err := reader.framer.AppendMessage(NewTimestampedMessageAt(message.Data[0], creationTime).InPartition(reader.partition).WithCreationTime(creationTime)); err != nil {
+		log.DefaultLogger.Error("Error adding message to frame", "message", string(message.Data[0]), "error", err)
+		return true
+	}
+	reader.framer.DropRowsOutside(reader.timeRange, firstRow)
+	if reader.framer.Rows() >= reader.maxRows {
+		reader.read.isTruncated = true
+		return false
+	}
+	return true
+}
+
+func getCreationTime(message *amqp.Message) (time.Time, bool) {
+	if message.Properties == nil || message.Properties.CreationTime.IsZero() {
+		return time.Time{}, false
+	}
+	return message.Properties.CreationTime, true
+}
@@ -0,0 +1,175 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/maormil/rabbitmq-datasource/pkg/rabbitmqclient"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+)
+
+// newHistoryMessage returns a message with the value v, created at the given time, or without a creation time when it is zero.
+func newHistoryMessage(v int, creationTime time.Time) *amqp.Message {
+	message := &amqp.Message{Data: [][]byte{[]byte(fmt.Sprintf(`{"v": %d}`, v))}}
+	if !creationTime.IsZero() {
+		message.Properties = &amqp.MessageProperties{CreationTime: creationTime}
+	}
+	return message
+}
+
+func TestReadHistory(t *testing.T) {
+	from := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	timeRange := backend.TimeRange{From: from, To: from.Add(10 * time.Minute)}
+	fieldMessage := func(v int, fieldTime time.Time) *amqp.Message {
+		return &amqp.Message{Data: [][]byte{[]byte(fmt.Sprintf(`{"v": %d, "time": %d}`, v, fieldTime.UnixMilli()))}}
+	}
+	tests := []struct {
+		name              string
+		timestampOptions  *TimestampOptions
+		endOffset         int64
+		maxRows           int
+		messages          []*amqp.Message
+		expectedValues    []interface{}
+		expectedTruncated bool
+		expectedUndated   int
+	}{
+		{
+			name:      "bounded by the time range",
+			endOffset: 10,
+			maxRows:   10,
+			messages: []*amqp.Message{
+				newHistoryMessage(1, from.Add(-time.Minute)),
+				newHistoryMessage(2, from.Add(time.Minute)),
+				newHistoryMessage(3, from.Add(5*time.Minute)),
+				newHistoryMessage(4, from.Add(11*time.Minute)),
+				newHistoryMessage(5, from.Add(2*time.Minute)),
+			},
+			expectedValues: []interface{}{float64(2), float64(3)},
+		},
+		{
+			name:      "undated messages",
+			endOffset: -1,
+			maxRows:   10,
+			messages: []*amqp.Message{
+				newHistoryMessage(1, time.Time{}),
+				newHistoryMessage(2, from.Add(time.Minute)),
+				{},
+				newHistoryMessage(3, time.Time{}),
+			},
+			expectedValues:  []interface{}{float64(2)},
+			expectedUndated: 2,
+		},
+		{
+			name:      "undated message at the end of the time range",
+			endOffset: 2,
+			maxRows:   10,
+			messages: []*amqp.Message{
+				newHistoryMessage(1, time.Time{}),
+				newHistoryMessage(2, from.Add(time.Minute)),
+				newHistoryMessage(3, time.Time{}),
+				newHistoryMessage(4, from.Add(2*time.Minute)),
+			},
+			expectedValues:  []interface{}{float64(2)},
+			expectedUndated: 1,
+		},
+		{
+			name:             "undated messages with the time in a field",
+			timestampOptions: &TimestampOptions{Source: TIMESTAMP_SOURCE_FIELD, Field: "$.time", Layout: TIMESTAMP_LAYOUT_EPOCH_MS},
+			endOffset:        -1,
+			maxRows:          10,
+			messages: []*amqp.Message{
+				fieldMessage(1, from.Add(time.Minute)),
+				fieldMessage(2, from.Add(-time.Minute)),
+				fieldMessage(3, from.Add(2*time.Minute)),
+			},
+			expectedValues: []interface{}{float64(1), float64(3)},
+		},
+		{
+			name:      "max rows",
+			endOffset: 10,
+			maxRows:   2,
+			messages: []*amqp.Message{
+				newHistoryMessage(1, from.Add(time.Minute)),
+				newHistoryMessage(2, from.Add(2*time.Minute)),
+				newHistoryMessage(3, from.Add(3*time.Minute)),
+			},
+			expectedValues:    []interface{}{float64(1), float64(2)},
+			expectedTruncated: true,
+		},
+		{
+			name:           "empty stream",
+			endOffset:      -1,
+			maxRows:        10,
+			expectedValues: []interface{}{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			framer := NewFramer(&FramerOptions{Timestamp: test.timestampOptions})
+			read := &historyRead{}
+			reader := &historyReader{
+				framer:      framer,
+				timeRange:   timeRange,
+				maxRows:     test.maxRows,
+				endOffset:   test.endOffset,
+				read:        read,
+				isFieldTime: test.timestampOptions.isFieldTime(),
+			}
+			// the offsets of the messages are their indexes
+			for offset, message := range test.messages {
+				if !reader.readMessage(message, func() int64 { return int64(offset) }) {
+					break
+				}
+			}
+
+			values := []interface{}{}
+			if field, _ := framer.Frame().FieldByName("v"); field != nil {
+				for index := 0; index < field.Len(); index += 1 {
+					value, _ := field.ConcreteAt(index)
+					values = append(values, value)
+				}
+			}
+			if !reflect.DeepEqual(values, test.expectedValues) {
+				t.Errorf("unexpected values: got %v, want %v", values, test.expectedValues)
+			}
+			if read.isTruncated != test.expectedTruncated {
+				t.Errorf("unexpected truncated: got %v, want %v", read.isTruncated, test.expectedTruncated)
+			}
+			if read.undatedMessages != test.expectedUndated {
+				t.Errorf("unexpected undated messages: got %d, want %d", read.undatedMessages, test.expectedUndated)
+			}
+		})
+	}
+}
+
+func TestQueryHistoryNotices(t *testing.T) {
+	from := time.Now().Add(-time.Hour)
+	client := &fakeClient{
+		streamOptions: &rabbitmqclient.StreamOptions{StreamName: "rabbitmq.stream"},
+		messages: []*amqp.Message{
+			newHistoryMessage(1, time.Time{}),
+			newHistoryMessage(2, from.Add(time.Minute)),
+			newHistoryMessage(3, from.Add(2*time.Minute)),
+		},
+	}
+	ds := &RabbitMQDatasource{Client: client}
+	frame, err := ds.queryHistory(context.Background(), &RabbitMQQuery{MaxRows: 1}, backend.TimeRange{From: from, To: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("queryHistory returned an error: %v", err)
+	}
+
+	notices := []string{}
+	if frame.Meta != nil {
+		for _, notice := range frame.Meta.Notices {
+			notices = append(notices, notice.Text)
+		}
+	}
+	if len(notices) != 2 || !strings.Contains(notices[0], "truncated to 1 rows") || !strings.HasPrefix(notices[1], "1 messages of the history were skipped") {
+		t.Errorf("unexpected notices: %q", notices)
+	}
+}
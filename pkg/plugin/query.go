@@ -9,12 +9,15 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/live"
 )
 
-func (ds *RabbitMQDatasource) QueryData(_ context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+func (ds *RabbitMQDatasource) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
 	log.DefaultLogger.Debug("Started QueryData method!")
 	response := backend.NewQueryDataResponse()
 
+	// alerting evaluates the queries without a browser, so there is no live channel to subscribe
+	isFromAlert := req.Headers["FromAlert"] == "true"
+
 	for _, q := range req.Queries {
-		res := ds.query(req.PluginContext, q)
+		res := ds.query(ctx, req.PluginContext, q, isFromAlert)
 
 		response.Responses[q.RefID] = res
 	}
@@ -24,22 +27,65 @@ func (ds *RabbitMQDatasource) QueryData(_ context.Context, req *backend.QueryDat
 	return response, nil
 }
 
-func (ds *RabbitMQDatasource) query(pCtx backend.PluginContext, query backend.DataQuery) backend.DataResponse {
+func (ds *RabbitMQDatasource) query(ctx context.Context, pCtx backend.PluginContext, query backend.DataQuery, isFromAlert bool) backend.DataResponse {
 	log.DefaultLogger.Debug("Started query method!")
 	response := backend.DataResponse{}
 
-	frame := data.NewFrame(FRAME_NAME)
+	rabbitmqQuery, err := NewRabbitMQQuery(query.JSON)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	channelPath, err := ds.Queries.Put(rabbitmqQuery)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	var frame *data.Frame
+	if rabbitmqQuery.LiveOnly && !isFromAlert {
+		frame = NewFramer(&rabbitmqQuery.FramerOptions).Frame()
+	} else {
+		frame, err = ds.queryHistory(ctx, rabbitmqQuery, query.TimeRange)
+	}
+	if err != nil {
+		message := "failed to read the history of the stream: " + err.Error()
+		if stateMessage := getConnectionStateMessage(ds.Client.GetConnectionState()); stateMessage != "" {
+			message = stateMessage + " - " + message
+		}
+		return backend.ErrDataResponse(backend.StatusInternal, message)
+	}
+
+	resampler := NewResampler(query, rabbitmqQuery.getAggregation(), isFromAlert)
+	if resampler.ShouldResample(frame) {
+		frame = resampler.Resample(frame)
+	}
+	if frame.Meta == nil {
+		frame.SetMeta(&data.FrameMeta{})
+	}
+	frame.Meta.Type = data.FrameTypeTimeSeriesWide
+	if message := getConnectionStateMessage(ds.Client.GetConnectionState()); message != "" {
+		frame.AppendNotices(data.Notice{Severity: data.NoticeSeverityWarning, Text: message})
+	}
+
+	if isFromAlert {
+		response.Frames = append(response.Frames, frame)
+		log.DefaultLogger.Debug("Finished query method for alerting!")
+		return response
+	}
 
 	channel := live.Channel{
 		Scope:     live.ScopeDatasource,
 		Namespace: pCtx.DataSourceInstanceSettings.UID,
-		Path:      STREAM_CHANNEL_PATH,
+		Path:      channelPath,
+	}
+	if _, err := live.ParseChannel(channel.String()); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, "the query can't be encoded into a live channel: "+err.Error())
 	}
-	frame.SetMeta(&data.FrameMeta{Channel: channel.String()})
+	frame.Meta.Channel = channel.String()
 
 	response.Frames = append(response.Frames, frame)
 
-	log.DefaultLogger.Debug("Finished query method!")
+	log.DefaultLogger.Debug("Finished query method!", "channel", channel.String())
 
 	return response
 }
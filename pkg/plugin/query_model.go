@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/maormil/rabbitmq-datasource/pkg/rabbitmqclient"
+)
+
+// RabbitMQQuery is the query model sent by the query editor. Every empty field
+// falls back to the stream settings of the datasource.
+type RabbitMQQuery struct {
+	StreamQuery
+	// LiveOnly skips the history of the time range, the alert rules still read it
+	LiveOnly    bool   `json:"liveOnly,omitempty"`
+	MaxRows     int    `json:"maxRows,omitempty"`
+	Aggregation string `json:"aggregation,omitempty"`
+}
+
+// StreamQuery holds the query fields that select the consumer of the live channel, and how its messages are framed.
+type StreamQuery struct {
+	FramerOptions
+	StreamName      string `json:"streamName,omitempty"`
+	ConsumerName    string `json:"consumerName,omitempty"`
+	OffsetFromStart *bool  `json:"offsetFromStart,omitempty"`
+	Crc             *bool  `json:"crc,omitempty"`
+	// OffsetOptions overrides both the offset of the datasource and OffsetFromStart
+	OffsetOptions *rabbitmqclient.OffsetOptions `json:"offsetOptions,omitempty"`
+	// SuperStream tells if the stream of the query is a super stream, Partitions chooses some of its partitions
+	SuperStream *bool    `json:"superStream,omitempty"`
+	Partitions  []string `json:"partitions,omitempty"`
+	// Filter overrides the filter of the datasource, so a panel only receives the chunks of its filter values
+	Filter *rabbitmqclient.StreamFilterOptions `json:"filter,omitempty"`
+	// Queue consumes a classic or quorum queue instead of the stream
+	Queue *rabbitmqclient.QueueConsumerOptions `json:"queue,omitempty"`
+}
+
+func NewRabbitMQQuery(queryJSON json.RawMessage) (*RabbitMQQuery, error) {
+	query := &RabbitMQQuery{}
+	if len(queryJSON) == 0 {
+		return query, nil
+	}
+	if err := json.Unmarshal(queryJSON, query); err != nil {
+		return nil, fmt.Errorf("failed to parse the query: %w", err)
+	}
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+func (query *RabbitMQQuery) validate() error {
+	if query.Aggregation != "" && !aggregations[query.Aggregation] {
+		return fmt.Errorf("unknown aggregation: %s", query.Aggregation)
+	}
+	if query.OffsetOptions != nil && query.OffsetOptions.Type != "" {
+		if err := query.OffsetOptions.Validate(); err != nil {
+			return fmt.Errorf("invalid offset of the query: %w", err)
+		}
+	}
+	if err := query.Filter.Validate(); err != nil {
+		return fmt.Errorf("invalid filter of the query: %w", err)
+	}
+	if err := query.Queue.Validate(); err != nil {
+		return fmt.Errorf("invalid queue of the query: %w", err)
+	}
+	if err := query.FramerOptions.Validate(); err != nil {
+		return fmt.Errorf("invalid framing of the query: %w", err)
+	}
+	return nil
+}
+
+func (query *RabbitMQQuery) getAggregation() string {
+	if query.Aggregation == "" {
+		return AGGREGATION_MEAN
+	}
+	return query.Aggregation
+}
+
+func (query *RabbitMQQuery) getMaxRows() int {
+	if query.MaxRows <= 0 {
+		return DEFAULT_MAX_ROWS
+	}
+	return query.MaxRows
+}
+
+// ToStreamOptions overrides the datasource stream options with the query fields. A datasource
+// without stream settings only serves the queries that name their own stream.
+func (query *RabbitMQQuery) ToStreamOptions(defaultStreamOptions *rabbitmqclient.StreamOptions) (*rabbitmqclient.StreamOptions, error) {
+	if defaultStreamOptions == nil {
+		if query.StreamName == "" {
+			return nil, fmt.Errorf("the query has no stream name, and the datasource has no stream settings")
+		}
+		defaultStreamOptions = &rabbitmqclient.StreamOptions{}
+	}
+	streamOptions := defaultStreamOptions.Clone()
+	if query.StreamName != "" && query.StreamName != defaultStreamOptions.StreamName {
+		streamOptions.StreamName = query.StreamName
+		// the datasource consumer name, partitions and filter belong to the datasource stream
+		streamOptions.ConsumerName = ""
+		streamOptions.SuperStream = false
+		streamOptions.ChosenPartitions = nil
+		streamOptions.Filter = nil
+	}
+	if query.SuperStream != nil {
+		streamOptions.SuperStream = *query.SuperStream
+	}
+	if len(query.Partitions) > 0 {
+		streamOptions.ChosenPartitions = query.Partitions
+	}
+	if query.ConsumerName != "" {
+		streamOptions.ConsumerName = query.ConsumerName
+	}
+	if query.OffsetFromStart != nil {
+		streamOptions.OffsetFromStart = *query.OffsetFromStart
+		streamOptions.OffsetOptions = nil
+	}
+	if query.OffsetOptions != nil && query.OffsetOptions.Type != "" {
+		streamOptions.OffsetOptions = query.OffsetOptions
+	}
+	if query.Crc != nil {
+		streamOptions.Crc = *query.Crc
+	}
+	if query.Filter.IsEnabled() {
+		streamOptions.Filter = query.Filter
+	}
+	return streamOptions, nil
+}
@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// channelHashLength is the number of bytes of the query hash in the channel path.
+	channelHashLength = 16
+	// unsubscribedQueryTTL is how long a query is kept while its channel has no subscriber.
+	unsubscribedQueryTTL = 10 * time.Minute
+)
+
+// QueryStore keeps the stream queries of the live channels by the hash in their channel path, since
+// the whole query (with its extractions, schema and filter) can be longer than a channel path.
+// A query is removed when the last subscriber of its channel leaves, or when its channel had no
+// subscriber for unsubscribedQueryTTL, so a channel without a query is unknown until its panel runs
+// the query again.
+type QueryStore struct {
+	mutex   sync.RWMutex
+	queries map[string]*storedQuery
+}
+
+type storedQuery struct {
+	query *RabbitMQQuery
+	// subscribers counts the running streams of the channel of the query
+	subscribers int
+	storedAt    time.Time
+}
+
+func NewQueryStore() *QueryStore {
+	return &QueryStore{
+		queries: make(map[string]*storedQuery),
+	}
+}
+
+// Put stores the stream query and returns its channel path. Identical queries share a channel.
+func (store *QueryStore) Put(query *RabbitMQQuery) (string, error) {
+	path, err := query.ToChannelPath()
+	if err != nil || path == STREAM_CHANNEL_PATH {
+		return path, err
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.removeUnsubscribed()
+	stored, exists := store.queries[path]
+	if !exists {
+		stored = &storedQuery{}
+		store.queries[path] = stored
+	}
+	stored.query = &RabbitMQQuery{StreamQuery: query.StreamQuery}
+	stored.storedAt = time.Now()
+	return path, nil
+}
+
+// removeUnsubscribed must be called while holding the mutex of the store.
+func (store *QueryStore) removeUnsubscribed() {
+	for path, stored := range store.queries {
+		if stored.subscribers == 0 && time.Since(stored.storedAt) > unsubscribedQueryTTL {
+			delete(store.queries, path)
+		}
+	}
+}
+
+// Get returns the stream query of a channel path that was returned by Put.
+// The bare STREAM_CHANNEL_PATH is the datasource stream with its own settings.
+func (store *QueryStore) Get(path string) (*RabbitMQQuery, error) {
+	if path == STREAM_CHANNEL_PATH {
+		return &RabbitMQQuery{}, nil
+	}
+	if !strings.HasPrefix(path, STREAM_CHANNEL_PATH+"/") {
+		return nil, fmt.Errorf("unknown channel path: %s", path)
+	}
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	stored, exists := store.queries[path]
+	if !exists {
+		return nil, fmt.Errorf("unknown channel path %s, the query must run again", path)
+	}
+	return stored.query, nil
+}
+
+// Subscribe returns the stream query of a channel path like Get, and keeps it until Unsubscribe
+// is called as many times for the channel.
+func (store *QueryStore) Subscribe(path string) (*RabbitMQQuery, error) {
+	if path == STREAM_CHANNEL_PATH {
+		return &RabbitMQQuery{}, nil
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, exists := store.queries[path]
+	if !exists {
+		return nil, fmt.Errorf("unknown channel path %s, the query must run again", path)
+	}
+	stored.subscribers += 1
+	return stored.query, nil
+}
+
+// Unsubscribe removes the query of the channel path with its last subscriber.
+func (store *QueryStore) Unsubscribe(path string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, exists := store.queries[path]
+	if !exists {
+		return
+	}
+	stored.subscribers -= 1
+	if stored.subscribers <= 0 {
+		delete(store.queries, path)
+	}
+}
+
+// ToChannelPath keys the query by the hash of its stream query, so RunStream can find the
+// consumer the query asked for in the query store. Identical queries share a channel.
+func (query *RabbitMQQuery) ToChannelPath() (string, error) {
+	queryJSON, err := json.Marshal(query.StreamQuery)
+	if err != nil {
+		return "", err
+	}
+	if string(queryJSON) == "{}" {
+		return STREAM_CHANNEL_PATH, nil
+	}
+	hash := sha256.Sum256(queryJSON)
+	return STREAM_CHANNEL_PATH + "/" + hex.EncodeToString(hash[:channelHashLength]), nil
+}
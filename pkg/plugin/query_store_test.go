@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maormil/rabbitmq-datasource/pkg/rabbitmqclient"
+)
+
+func TestQueryStore(t *testing.T) {
+	offsetFromStart := true
+	tests := []struct {
+		name         string
+		query        *RabbitMQQuery
+		expectedPath string
+	}{
+		{
+			name:         "datasource stream",
+			query:        &RabbitMQQuery{MaxRows: 100},
+			expectedPath: STREAM_CHANNEL_PATH,
+		},
+		{
+			name: "stream query",
+			query: &RabbitMQQuery{StreamQuery: StreamQuery{
+				StreamName:      "rabbitmq.stream",
+				OffsetFromStart: &offsetFromStart,
+				FramerOptions: FramerOptions{
+					Flatten:     &FlattenOptions{Separator: "_"},
+					Extractions: []*Extraction{{Expression: "$.payload." + strings.Repeat("field.", 100) + "value"}},
+				},
+			}},
+		},
+		{
+			name: "queue query",
+			query: &RabbitMQQuery{StreamQuery: StreamQuery{
+				Queue: &rabbitmqclient.QueueConsumerOptions{Exchange: "rabbitmq.exchange", RoutingKey: "#"},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewQueryStore()
+			path, err := store.Put(test.query)
+			if err != nil {
+				t.Fatalf("Put returned an error: %v", err)
+			}
+			if test.expectedPath != "" && path != test.expectedPath {
+				t.Errorf("unexpected channel path: got %s, want %s", path, test.expectedPath)
+			}
+			// the channel path is bounded whatever the length of the query
+			if len(path) > len(STREAM_CHANNEL_PATH)+1+2*channelHashLength {
+				t.Errorf("the channel path is too long: %s", path)
+			}
+
+			query, err := store.Get(path)
+			if err != nil {
+				t.Fatalf("Get returned an error: %v", err)
+			}
+			if !reflect.DeepEqual(query.StreamQuery, test.query.StreamQuery) {
+				t.Errorf("unexpected query: got %+v, want %+v", query.StreamQuery, test.query.StreamQuery)
+			}
+		})
+	}
+}
+
+func TestQueryStoreSharesChannels(t *testing.T) {
+	store := NewQueryStore()
+	path, _ := store.Put(&RabbitMQQuery{StreamQuery: StreamQuery{StreamName: "rabbitmq.stream"}, MaxRows: 100})
+	// the fields that don't select the consumer don't change the channel
+	samePath, _ := store.Put(&RabbitMQQuery{StreamQuery: StreamQuery{StreamName: "rabbitmq.stream"}, MaxRows: 10})
+	otherPath, _ := store.Put(&RabbitMQQuery{StreamQuery: StreamQuery{StreamName: "rabbitmq.other"}})
+
+	if path != samePath {
+		t.Errorf("identical stream queries have different channels: %s and %s", path, samePath)
+	}
+	if path == otherPath {
+		t.Errorf("different stream queries share the channel %s", path)
+	}
+}
+
+func TestQueryStoreUnknownPath(t *testing.T) {
+	store := NewQueryStore()
+	for _, path := range []string{STREAM_CHANNEL_PATH + "/0123456789abcdef0123456789abcdef", "other"} {
+		if _, err := store.Get(path); err == nil {
+			t.Errorf("the unknown channel path %s was found", path)
+		}
+	}
+}
+
+func TestToStreamOptionsWithoutDatasourceStream(t *testing.T) {
+	if _, err := (&RabbitMQQuery{}).ToStreamOptions(nil); err == nil {
+		t.Errorf("a query without a stream name was accepted by a datasource without a stream")
+	}
+	streamOptions, err := (&RabbitMQQuery{StreamQuery: StreamQuery{StreamName: "rabbitmq.stream"}}).ToStreamOptions(nil)
+	if err != nil {
+		t.Fatalf("ToStreamOptions returned an error: %v", err)
+	}
+	if streamOptions.StreamName != "rabbitmq.stream" {
+		t.Errorf("unexpected stream name: got %s, want rabbitmq.stream", streamOptions.StreamName)
+	}
+}
+
+func TestQueryStoreRemovesQueries(t *testing.T) {
+	store := NewQueryStore()
+	path, _ := store.Put(&RabbitMQQuery{StreamQuery: StreamQuery{StreamName: "rabbitmq.stream"}})
+
+	// the query is kept until the last subscriber of its channel leaves
+	for index := 0; index < 2; index += 1 {
+		if _, err := store.Subscribe(path); err != nil {
+			t.Fatalf("Subscribe returned an error: %v", err)
+		}
+	}
+	store.Unsubscribe(path)
+	if _, err := store.Get(path); err != nil {
+		t.Errorf("the query was removed while its channel has a subscriber: %v", err)
+	}
+	store.Unsubscribe(path)
+	if _, err := store.Get(path); err == nil {
+		t.Errorf("the query was kept after the last subscriber of its channel left")
+	}
+
+	// the queries whose channel has no subscriber expire, the others are kept
+	unsubscribedPath, _ := store.Put(&RabbitMQQuery{StreamQuery: StreamQuery{StreamName: "rabbitmq.unsubscribed"}})
+	subscribedPath, _ := store.Put(&RabbitMQQuery{StreamQuery: StreamQuery{StreamName: "rabbitmq.subscribed"}})
+	if _, err := store.Subscribe(subscribedPath); err != nil {
+		t.Fatalf("Subscribe returned an error: %v", err)
+	}
+	for _, stored := range store.queries {
+		stored.storedAt = time.Now().Add(-2 * unsubscribedQueryTTL)
+	}
+	_, _ = store.Put(&RabbitMQQuery{StreamQuery: StreamQuery{StreamName: "rabbitmq.stream"}})
+	if _, err := store.Get(unsubscribedPath); err == nil {
+		t.Errorf("the query without a subscriber did not expire")
+	}
+	if _, err := store.Get(subscribedPath); err != nil {
+		t.Errorf("the query of a subscribed channel expired: %v", err)
+	}
+}
+
+func TestQueryStoreDatasourceStream(t *testing.T) {
+	store := NewQueryStore()
+	if _, err := store.Subscribe(STREAM_CHANNEL_PATH); err != nil {
+		t.Fatalf("Subscribe returned an error: %v", err)
+	}
+	store.Unsubscribe(STREAM_CHANNEL_PATH)
+	if _, err := store.Get(STREAM_CHANNEL_PATH); err != nil {
+		t.Errorf("the datasource stream is unknown: %v", err)
+	}
+}
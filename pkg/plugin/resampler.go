@@ -0,0 +1,214 @@
+package plugin
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	AGGREGATION_MEAN  = "mean"
+	AGGREGATION_MIN   = "min"
+	AGGREGATION_MAX   = "max"
+	AGGREGATION_SUM   = "sum"
+	AGGREGATION_COUNT = "count"
+	AGGREGATION_FIRST = "first"
+	AGGREGATION_LAST  = "last"
+)
+
+var aggregations = map[string]bool{
+	AGGREGATION_MEAN:  true,
+	AGGREGATION_MIN:   true,
+	AGGREGATION_MAX:   true,
+	AGGREGATION_SUM:   true,
+	AGGREGATION_COUNT: true,
+	AGGREGATION_FIRST: true,
+	AGGREGATION_LAST:  true,
+}
+
+// Resampler groups the rows of a frame into time buckets and aggregates every bucket into a single row.
+type Resampler struct {
+	TimeRange     backend.TimeRange
+	Interval      time.Duration
+	MaxDataPoints int64
+	Aggregation   string
+	// NumericOnly drops the fields that can't be evaluated by alerting, booleans are converted to 0/1.
+	NumericOnly bool
+}
+
+type bucketAggregator struct {
+	count int
+	sum   float64
+	min   float64
+	max   float64
+	first *float64
+	last  *float64
+}
+
+func NewResampler(query backend.DataQuery, aggregation string, numericOnly bool) *Resampler {
+	return &Resampler{
+		TimeRange:     query.TimeRange,
+		Interval:      query.Interval,
+		MaxDataPoints: query.MaxDataPoints,
+		Aggregation:   aggregation,
+		NumericOnly:   numericOnly,
+	}
+}
+
+// ShouldResample tells if the frame has more rows than the panel can show, or must be numeric for alerting.
+func (resampler *Resampler) ShouldResample(frame *data.Frame) bool {
+	if resampler.NumericOnly {
+		return true
+	}
+	return resampler.MaxDataPoints > 0 && int64(frame.Rows()) > resampler.MaxDataPoints
+}
+
+// getBucketSize returns the query interval, widened so the time range fits in the max data points.
+func (resampler *Resampler) getBucketSize() time.Duration {
+	bucketSize := resampler.Interval
+	if resampler.MaxDataPoints > 0 {
+		minBucketSize := resampler.TimeRange.Duration() / time.Duration(resampler.MaxDataPoints)
+		if minBucketSize > bucketSize {
+			bucketSize = minBucketSize
+		}
+	}
+	if bucketSize <= 0 {
+		return time.Millisecond
+	}
+	return bucketSize
+}
+
+// Resample returns a new frame with a row per time bucket that has rows, sorted by time. The rows outside
+// of the time range are dropped, and the buckets without rows are left out. The first field of the frame must be its time field.
+func (resampler *Resampler) Resample(frame *data.Frame) *data.Frame {
+	bucketSize := resampler.getBucketSize()
+	timeField := frame.Fields[0]
+
+	rowsByBucket := make(map[int64][]int)
+	for rowIdx := 0; rowIdx < timeField.Len(); rowIdx++ {
+		timestamp, ok := timeField.ConcreteAt(rowIdx)
+		if !ok || !resampler.isInTimeRange(timestamp.(time.Time)) {
+			continue
+		}
+		bucket := timestamp.(time.Time).Sub(resampler.TimeRange.From).Nanoseconds() / bucketSize.Nanoseconds()
+		rowsByBucket[bucket] = append(rowsByBucket[bucket], rowIdx)
+	}
+	buckets := make([]int64, 0, len(rowsByBucket))
+	for bucket := range rowsByBucket {
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+
+	resampledTimeField := data.NewFieldFromFieldType(data.FieldTypeTime, 0)
+	resampledTimeField.Name = timeField.Name
+	for _, bucket := range buckets {
+		resampledTimeField.Append(resampler.TimeRange.From.Add(time.Duration(bucket) * bucketSize))
+	}
+	resampledFields := []*data.Field{resampledTimeField}
+
+	for _, field := range frame.Fields[1:] {
+		var resampledField *data.Field
+		switch {
+		case field.Type().Numeric() || (resampler.NumericOnly && field.Type().NonNullableType() == data.FieldTypeBool):
+			resampledField = resampler.aggregateField(field, buckets, rowsByBucket)
+		case resampler.NumericOnly:
+			continue
+		default:
+			resampledField = lastValueField(field, buckets, rowsByBucket)
+		}
+		resampledField.Name = field.Name
+		resampledField.Labels = field.Labels
+		resampledField.Config = field.Config
+		resampledFields = append(resampledFields, resampledField)
+	}
+
+	resampledFrame := data.NewFrame(frame.Name, resampledFields...)
+	resampledFrame.Meta = frame.Meta
+	return resampledFrame
+}
+
+// isInTimeRange tells if the timestamp is in the time range, a resampler without a time range keeps every row.
+func (resampler *Resampler) isInTimeRange(timestamp time.Time) bool {
+	if resampler.TimeRange.From.IsZero() && resampler.TimeRange.To.IsZero() {
+		return true
+	}
+	return !timestamp.Before(resampler.TimeRange.From) && !timestamp.After(resampler.TimeRange.To)
+}
+
+func (resampler *Resampler) aggregateField(field *data.Field, buckets []int64, rowsByBucket map[int64][]int) *data.Field {
+	resampledField := data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, 0)
+	for _, bucket := range buckets {
+		aggregator := &bucketAggregator{min: math.Inf(1), max: math.Inf(-1)}
+		for _, rowIdx := range rowsByBucket[bucket] {
+			if value, ok := getFloat(field, rowIdx); ok {
+				aggregator.add(value)
+			}
+		}
+		resampledField.Append(aggregator.result(resampler.Aggregation))
+	}
+	return resampledField
+}
+
+func lastValueField(field *data.Field, buckets []int64, rowsByBucket map[int64][]int) *data.Field {
+	resampledField := data.NewFieldFromFieldType(field.Type(), 0)
+	for _, bucket := range buckets {
+		rows := rowsByBucket[bucket]
+		resampledField.Append(field.At(rows[len(rows)-1]))
+	}
+	return resampledField
+}
+
+func getFloat(field *data.Field, rowIdx int) (float64, bool) {
+	value, ok := field.ConcreteAt(rowIdx)
+	if !ok {
+		return 0, false
+	}
+	if boolValue, isBool := value.(bool); isBool {
+		if boolValue {
+			return 1, true
+		}
+		return 0, true
+	}
+	floatValue, err := field.FloatAt(rowIdx)
+	return floatValue, err == nil
+}
+
+func (aggregator *bucketAggregator) add(value float64) {
+	aggregator.count += 1
+	aggregator.sum += value
+	aggregator.min = math.Min(aggregator.min, value)
+	aggregator.max = math.Max(aggregator.max, value)
+	if aggregator.first == nil {
+		aggregator.first = &value
+	}
+	aggregator.last = &value
+}
+
+func (aggregator *bucketAggregator) result(aggregation string) *float64 {
+	if aggregation == AGGREGATION_COUNT {
+		count := float64(aggregator.count)
+		return &count
+	}
+	if aggregator.count == 0 {
+		return nil
+	}
+	var result float64
+	switch aggregation {
+	case AGGREGATION_MIN:
+		result = aggregator.min
+	case AGGREGATION_MAX:
+		result = aggregator.max
+	case AGGREGATION_SUM:
+		result = aggregator.sum
+	case AGGREGATION_FIRST:
+		return aggregator.first
+	case AGGREGATION_LAST:
+		return aggregator.last
+	default:
+		result = aggregator.sum / float64(aggregator.count)
+	}
+	return &result
+}
@@ -0,0 +1,172 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+var resamplerFrom = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newResamplerFrame returns a frame with a value field and a label field, and a row for every offset from resamplerFrom.
+func newResamplerFrame(offsets []time.Duration, values []*float64) *data.Frame {
+	timeField := data.NewFieldFromFieldType(data.FieldTypeTime, 0)
+	timeField.Name = TIMESTAMP_NAME
+	valueField := data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, 0)
+	valueField.Name = "value"
+	labelField := data.NewFieldFromFieldType(data.FieldTypeNullableString, 0)
+	labelField.Name = "label"
+	for index, offset := range offsets {
+		timeField.Append(resamplerFrom.Add(offset))
+		valueField.Append(values[index])
+		label := offset.String()
+		labelField.Append(&label)
+	}
+	return data.NewFrame(FRAME_NAME, timeField, valueField, labelField)
+}
+
+func float(value float64) *float64 {
+	return &value
+}
+
+func fieldValues(field *data.Field) []interface{} {
+	values := make([]interface{}, field.Len())
+	for index := range values {
+		if value, ok := field.ConcreteAt(index); ok {
+			values[index] = value
+		}
+	}
+	return values
+}
+
+func TestResampleAggregations(t *testing.T) {
+	// two rows in the first bucket, no rows in the second bucket and a row in the third bucket
+	offsets := []time.Duration{0, 30 * time.Second, 150 * time.Second}
+	values := []*float64{float(1), float(3), float(10)}
+	tests := []struct {
+		aggregation    string
+		expectedValues []interface{}
+	}{
+		{aggregation: AGGREGATION_MEAN, expectedValues: []interface{}{float64(2), float64(10)}},
+		{aggregation: AGGREGATION_MIN, expectedValues: []interface{}{float64(1), float64(10)}},
+		{aggregation: AGGREGATION_MAX, expectedValues: []interface{}{float64(3), float64(10)}},
+		{aggregation: AGGREGATION_SUM, expectedValues: []interface{}{float64(4), float64(10)}},
+		{aggregation: AGGREGATION_COUNT, expectedValues: []interface{}{float64(2), float64(1)}},
+		{aggregation: AGGREGATION_FIRST, expectedValues: []interface{}{float64(1), float64(10)}},
+		{aggregation: AGGREGATION_LAST, expectedValues: []interface{}{float64(3), float64(10)}},
+	}
+	for _, test := range tests {
+		t.Run(test.aggregation, func(t *testing.T) {
+			resampler := &Resampler{
+				TimeRange:   backend.TimeRange{From: resamplerFrom, To: resamplerFrom.Add(5 * time.Minute)},
+				Interval:    time.Minute,
+				Aggregation: test.aggregation,
+			}
+			frame := resampler.Resample(newResamplerFrame(offsets, values))
+
+			expectedTimes := []interface{}{resamplerFrom, resamplerFrom.Add(2 * time.Minute)}
+			if times := fieldValues(frame.Fields[0]); !reflect.DeepEqual(times, expectedTimes) {
+				t.Errorf("unexpected buckets: got %v, want %v", times, expectedTimes)
+			}
+			if got := fieldValues(frame.Fields[1]); !reflect.DeepEqual(got, test.expectedValues) {
+				t.Errorf("unexpected values: got %v, want %v", got, test.expectedValues)
+			}
+			// the fields that are not numeric keep the last value of their bucket
+			if labels := fieldValues(frame.Fields[2]); !reflect.DeepEqual(labels, []interface{}{"30s", "2m30s"}) {
+				t.Errorf("unexpected labels: %v", labels)
+			}
+		})
+	}
+}
+
+func TestResampleBuckets(t *testing.T) {
+	tests := []struct {
+		name           string
+		resampler      *Resampler
+		offsets        []time.Duration
+		values         []*float64
+		expectedTimes  []interface{}
+		expectedValues []interface{}
+	}{
+		{
+			name:           "the interval is widened to fit the max data points",
+			resampler:      &Resampler{Interval: time.Second, MaxDataPoints: 2},
+			offsets:        []time.Duration{0, 10 * time.Second, 70 * time.Second},
+			values:         []*float64{float(1), float(2), float(3)},
+			expectedTimes:  []interface{}{resamplerFrom, resamplerFrom.Add(time.Minute)},
+			expectedValues: []interface{}{float64(1.5), float64(3)},
+		},
+		{
+			name:           "the rows outside of the time range are dropped",
+			resampler:      &Resampler{Interval: time.Minute},
+			offsets:        []time.Duration{-time.Minute, 0, 3 * time.Minute},
+			values:         []*float64{float(100), float(1), float(100)},
+			expectedTimes:  []interface{}{resamplerFrom},
+			expectedValues: []interface{}{float64(1)},
+		},
+		{
+			name:           "a bucket without values is empty",
+			resampler:      &Resampler{Interval: time.Minute},
+			offsets:        []time.Duration{0, time.Minute},
+			values:         []*float64{float(1), nil},
+			expectedTimes:  []interface{}{resamplerFrom, resamplerFrom.Add(time.Minute)},
+			expectedValues: []interface{}{float64(1), nil},
+		},
+		{
+			name:           "an empty frame has no buckets",
+			resampler:      &Resampler{Interval: time.Minute},
+			expectedTimes:  []interface{}{},
+			expectedValues: []interface{}{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.resampler.TimeRange = backend.TimeRange{From: resamplerFrom, To: resamplerFrom.Add(2 * time.Minute)}
+			test.resampler.Aggregation = AGGREGATION_MEAN
+			frame := test.resampler.Resample(newResamplerFrame(test.offsets, test.values))
+
+			if times := fieldValues(frame.Fields[0]); !reflect.DeepEqual(times, test.expectedTimes) {
+				t.Errorf("unexpected buckets: got %v, want %v", times, test.expectedTimes)
+			}
+			if got := fieldValues(frame.Fields[1]); !reflect.DeepEqual(got, test.expectedValues) {
+				t.Errorf("unexpected values: got %v, want %v", got, test.expectedValues)
+			}
+		})
+	}
+}
+
+func TestResampleNumericOnly(t *testing.T) {
+	frame := newResamplerFrame([]time.Duration{0, time.Second}, []*float64{float(1), float(2)})
+	boolField := data.NewFieldFromFieldType(data.FieldTypeNullableBool, 0)
+	boolField.Name = "ok"
+	isOk, isNotOk := true, false
+	boolField.Append(&isOk)
+	boolField.Append(&isNotOk)
+	frame.Fields = append(frame.Fields, boolField)
+
+	resampler := &Resampler{
+		TimeRange:   backend.TimeRange{From: resamplerFrom, To: resamplerFrom.Add(time.Minute)},
+		Interval:    time.Minute,
+		Aggregation: AGGREGATION_SUM,
+		NumericOnly: true,
+	}
+	if !resampler.ShouldResample(frame) {
+		t.Fatalf("the frames of the alert rules must be resampled")
+	}
+	resampledFrame := resampler.Resample(frame)
+
+	names := make([]string, len(resampledFrame.Fields))
+	for index, field := range resampledFrame.Fields {
+		names[index] = field.Name
+	}
+	// the label field is dropped, and the booleans are counted as 0/1
+	if !reflect.DeepEqual(names, []string{TIMESTAMP_NAME, "value", "ok"}) {
+		t.Errorf("unexpected fields: %v", names)
+	}
+	if got := fieldValues(resampledFrame.Fields[2]); !reflect.DeepEqual(got, []interface{}{float64(1)}) {
+		t.Errorf("unexpected boolean values: %v", got)
+	}
+}
@@ -0,0 +1,185 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// SchemaField declares the type of a field, so the frames keep the same schema: the field exists from
+// the first frame, and its values are converted to its type instead of being dropped when they arrive
+// with another type (like a number that was published as a string).
+type SchemaField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Unit string `json:"unit,omitempty"`
+	// Nullable leaves the missing and rejected values empty, otherwise they are the default or the zero value
+	// of the type, and a time field must have a default since it has no meaningful zero value
+	Nullable bool `json:"nullable,omitempty"`
+	// Default replaces the missing and rejected values
+	Default interface{} `json:"default,omitempty"`
+}
+
+// schemaFieldTypes are the field types of the fields that are not nullable.
+var schemaFieldTypes = map[string]data.FieldType{
+	FIELD_TYPE_NUMBER:  data.FieldTypeFloat64,
+	FIELD_TYPE_STRING:  data.FieldTypeString,
+	FIELD_TYPE_BOOLEAN: data.FieldTypeBool,
+	FIELD_TYPE_TIME:    data.FieldTypeTime,
+	FIELD_TYPE_JSON:    data.FieldTypeJSON,
+}
+
+// zeroValues are the values of the missing values of the fields that are not nullable and have no default.
+var zeroValues = map[string]interface{}{
+	FIELD_TYPE_NUMBER:  float64(0),
+	FIELD_TYPE_STRING:  "",
+	FIELD_TYPE_BOOLEAN: false,
+	FIELD_TYPE_JSON:    nil,
+}
+
+func (schemaField *SchemaField) Validate() error {
+	if schemaField.Name == "" {
+		return fmt.Errorf("invalid schema: the field name must not be empty")
+	}
+	if _, exists := schemaFieldTypes[schemaField.Type]; !exists {
+		return fmt.Errorf("invalid schema field %s: unknown type %q, the type must be one of %s, %s, %s, %s or %s", schemaField.Name, schemaField.Type,
+			FIELD_TYPE_NUMBER, FIELD_TYPE_STRING, FIELD_TYPE_BOOLEAN, FIELD_TYPE_TIME, FIELD_TYPE_JSON)
+	}
+	if schemaField.Name == TIMESTAMP_NAME {
+		return fmt.Errorf("invalid schema field %s: the field is the timestamp of the messages", schemaField.Name)
+	}
+	if schemaField.Default != nil {
+		if _, _, err := coerceValue(schemaField.Default, schemaField.Type); err != nil {
+			return fmt.Errorf("invalid schema field %s: the default value is not a %s: %w", schemaField.Name, schemaField.Type, err)
+		}
+	} else if _, hasZeroValue := zeroValues[schemaField.Type]; !hasZeroValue && !schemaField.Nullable {
+		return fmt.Errorf("invalid schema field %s: a %s field that is not nullable must have a default value", schemaField.Name, schemaField.Type)
+	}
+	return nil
+}
+
+func (schemaField *SchemaField) getFieldType() data.FieldType {
+	fieldType := schemaFieldTypes[schemaField.Type]
+	if schemaField.Nullable {
+		return fieldType.NullableType()
+	}
+	return fieldType
+}
+
+func (schemaField *SchemaField) newField() *data.Field {
+	field := data.NewFieldFromFieldType(schemaField.getFieldType(), 0)
+	field.Name = schemaField.Name
+	if schemaField.Unit != "" {
+		field.Config = &data.FieldConfig{Unit: schemaField.Unit}
+	}
+	return field
+}
+
+// missingValue returns the value of a missing or rejected value, nil leaves it empty.
+func (schemaField *SchemaField) missingValue() interface{} {
+	value := schemaField.Default
+	if value == nil {
+		if schemaField.Nullable {
+			return nil
+		}
+		value = zeroValues[schemaField.Type]
+	}
+	fieldValue, err := schemaField.toFieldValue(value)
+	if err != nil {
+		return nil
+	}
+	return fieldValue
+}
+
+// toFieldValue converts the value to the type of the schema field, a pointer when the field is nullable.
+func (schemaField *SchemaField) toFieldValue(value interface{}) (interface{}, error) {
+	_, fieldValue, err := coerceValue(value, schemaField.Type)
+	if err != nil {
+		return nil, err
+	}
+	if schemaField.Nullable {
+		if rawMessage, isJSON := fieldValue.(json.RawMessage); isJSON {
+			return &rawMessage, nil
+		}
+		return fieldValue, nil
+	}
+	switch typedValue := fieldValue.(type) {
+	case *float64:
+		return *typedValue, nil
+	case *string:
+		return *typedValue, nil
+	case *bool:
+		return *typedValue, nil
+	case *time.Time:
+		return *typedValue, nil
+	}
+	return fieldValue, nil
+}
+
+// addSchemaValue converts the value to the type of its schema field, a value that can't be converted is rejected.
+func (df *Framer) addSchemaValue(schemaField *SchemaField, value interface{}) {
+	field := df.Fields[df.FieldMap[schemaField.Name]]
+	if field.Len() > df.Fields[0].Len() {
+		// the field already has a value in this row
+		return
+	}
+	fieldValue, err := schemaField.toFieldValue(fromFieldValue(value))
+	if err != nil {
+		df.rejectValue(schemaField.Name)
+		return
+	}
+	field.Append(fieldValue)
+}
+
+// fillSchemaFields adds the missing value to the schema fields that have no value in the current row.
+func (df *Framer) fillSchemaFields() {
+	for _, schemaField := range df.Options.Schema {
+		field := df.Fields[df.FieldMap[schemaField.Name]]
+		if field.Len() > df.Fields[0].Len() {
+			continue
+		}
+		if value := schemaField.missingValue(); value != nil {
+			field.Append(value)
+		} else {
+			field.Extend(1)
+		}
+	}
+}
+
+func (df *Framer) rejectValue(key string) {
+	df.rejectedValues[key] += 1
+	df.pendingRejections[key] += 1
+}
+
+// getRejectionsNotice describes the values that were rejected since the last frame.
+func (df *Framer) getRejectionsNotice() *data.Notice {
+	if len(df.pendingRejections) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(df.pendingRejections))
+	for key := range df.pendingRejections {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	rejections := make([]string, len(keys))
+	for index, key := range keys {
+		rejections[index] = fmt.Sprintf("%s (%d)", key, df.pendingRejections[key])
+	}
+	return &data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text:     fmt.Sprintf("Values that don't match the type of their field were rejected: %s", strings.Join(rejections, ", ")),
+	}
+}
+
+// getRejectedValues returns the number of the rejected values of every field since the framer was created.
+func (df *Framer) getRejectedValues() map[string]int64 {
+	rejectedValues := make(map[string]int64, len(df.rejectedValues))
+	for key, count := range df.rejectedValues {
+		rejectedValues[key] = count
+	}
+	return rejectedValues
+}
@@ -0,0 +1,177 @@
+package plugin
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+var schemaDefaultTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestValidateSchemaField(t *testing.T) {
+	tests := []struct {
+		name        string
+		schemaField *SchemaField
+		expectError bool
+	}{
+		{
+			name:        "number field",
+			schemaField: &SchemaField{Name: "value", Type: FIELD_TYPE_NUMBER},
+		},
+		{
+			name:        "nullable time field",
+			schemaField: &SchemaField{Name: "createdAt", Type: FIELD_TYPE_TIME, Nullable: true},
+		},
+		{
+			name:        "time field with a default",
+			schemaField: &SchemaField{Name: "createdAt", Type: FIELD_TYPE_TIME, Default: "2024-01-01T00:00:00Z"},
+		},
+		{
+			// a missing time would be the epoch
+			name:        "time field that is not nullable without a default",
+			schemaField: &SchemaField{Name: "createdAt", Type: FIELD_TYPE_TIME},
+			expectError: true,
+		},
+		{
+			name:        "default of another type",
+			schemaField: &SchemaField{Name: "value", Type: FIELD_TYPE_NUMBER, Default: "high"},
+			expectError: true,
+		},
+		{
+			name:        "unknown type",
+			schemaField: &SchemaField{Name: "value", Type: "integer"},
+			expectError: true,
+		},
+		{
+			name:        "timestamp field",
+			schemaField: &SchemaField{Name: TIMESTAMP_NAME, Type: FIELD_TYPE_TIME, Nullable: true},
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.schemaField.Validate()
+			if (err != nil) != test.expectError {
+				t.Errorf("unexpected validation error: got %v, want an error: %v", err, test.expectError)
+			}
+		})
+	}
+}
+
+func TestSchemaFieldTypes(t *testing.T) {
+	tests := []struct {
+		fieldType        string
+		expectedType     data.FieldType
+		expectedNullable data.FieldType
+	}{
+		{fieldType: FIELD_TYPE_NUMBER, expectedType: data.FieldTypeFloat64, expectedNullable: data.FieldTypeNullableFloat64},
+		{fieldType: FIELD_TYPE_STRING, expectedType: data.FieldTypeString, expectedNullable: data.FieldTypeNullableString},
+		{fieldType: FIELD_TYPE_BOOLEAN, expectedType: data.FieldTypeBool, expectedNullable: data.FieldTypeNullableBool},
+		{fieldType: FIELD_TYPE_TIME, expectedType: data.FieldTypeTime, expectedNullable: data.FieldTypeNullableTime},
+		{fieldType: FIELD_TYPE_JSON, expectedType: data.FieldTypeJSON, expectedNullable: data.FieldTypeNullableJSON},
+	}
+	for _, test := range tests {
+		t.Run(test.fieldType, func(t *testing.T) {
+			schemaField := &SchemaField{Name: "value", Type: test.fieldType}
+			if fieldType := schemaField.newField().Type(); fieldType != test.expectedType {
+				t.Errorf("unexpected field type: got %s, want %s", fieldType, test.expectedType)
+			}
+			schemaField.Nullable = true
+			if fieldType := schemaField.newField().Type(); fieldType != test.expectedNullable {
+				t.Errorf("unexpected nullable field type: got %s, want %s", fieldType, test.expectedNullable)
+			}
+		})
+	}
+}
+
+func TestSchemaCoercion(t *testing.T) {
+	messages := []string{
+		`{"value": "5", "label": 3, "enabled": "false", "createdAt": 1704067200000, "payload": [1, 2]}`,
+		`{"value": "high", "enabled": 1}`,
+		`{"label": "sensor"}`,
+	}
+	tests := []struct {
+		name             string
+		schemaField      *SchemaField
+		expectedValues   []interface{}
+		expectedRejected int64
+	}{
+		{
+			name:             "number",
+			schemaField:      &SchemaField{Name: "value", Type: FIELD_TYPE_NUMBER},
+			expectedValues:   []interface{}{float64(5), float64(0), float64(0)},
+			expectedRejected: 1,
+		},
+		{
+			name:             "nullable number",
+			schemaField:      &SchemaField{Name: "value", Type: FIELD_TYPE_NUMBER, Nullable: true},
+			expectedValues:   []interface{}{float64(5), nil, nil},
+			expectedRejected: 1,
+		},
+		{
+			name:             "number with a default",
+			schemaField:      &SchemaField{Name: "value", Type: FIELD_TYPE_NUMBER, Nullable: true, Default: float64(-1)},
+			expectedValues:   []interface{}{float64(5), float64(-1), float64(-1)},
+			expectedRejected: 1,
+		},
+		{
+			name:           "string",
+			schemaField:    &SchemaField{Name: "label", Type: FIELD_TYPE_STRING},
+			expectedValues: []interface{}{"3", "", "sensor"},
+		},
+		{
+			name:           "boolean",
+			schemaField:    &SchemaField{Name: "enabled", Type: FIELD_TYPE_BOOLEAN, Nullable: true},
+			expectedValues: []interface{}{false, true, nil},
+		},
+		{
+			name:           "time with a default",
+			schemaField:    &SchemaField{Name: "createdAt", Type: FIELD_TYPE_TIME, Default: "2024-01-01T00:00:00Z"},
+			expectedValues: []interface{}{time.UnixMilli(1704067200000), schemaDefaultTime, schemaDefaultTime},
+		},
+		{
+			name:           "json",
+			schemaField:    &SchemaField{Name: "payload", Type: FIELD_TYPE_JSON},
+			expectedValues: []interface{}{json.RawMessage(`[1,2]`), json.RawMessage(`null`), json.RawMessage(`null`)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.schemaField.Validate(); err != nil {
+				t.Fatalf("invalid schema field: %v", err)
+			}
+			framer := NewFramer(&FramerOptions{Schema: []*SchemaField{test.schemaField}})
+			for _, message := range messages {
+				if err := framer.AppendMessage(NewTimestampedMessage([]byte(message))); err != nil {
+					t.Fatalf("AppendMessage returned an error: %v", err)
+				}
+			}
+
+			frame := framer.Frame()
+			field, _ := frame.FieldByName(test.schemaField.Name)
+			if field == nil {
+				t.Fatalf("the schema field %s is missing", test.schemaField.Name)
+			}
+			values := fieldValues(field)
+			for index, value := range values {
+				if timestamp, isTime := value.(time.Time); isTime {
+					values[index] = timestamp.UTC()
+				}
+			}
+			for index, value := range test.expectedValues {
+				if timestamp, isTime := value.(time.Time); isTime {
+					test.expectedValues[index] = timestamp.UTC()
+				}
+			}
+			if !reflect.DeepEqual(values, test.expectedValues) {
+				t.Errorf("unexpected values: got %v, want %v", values, test.expectedValues)
+			}
+			if rejected := framer.getRejectedValues()[test.schemaField.Name]; rejected != test.expectedRejected {
+				t.Errorf("unexpected rejected values: got %d, want %d", rejected, test.expectedRejected)
+			}
+		})
+	}
+}
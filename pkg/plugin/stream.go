@@ -2,74 +2,238 @@ package plugin
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/maormil/rabbitmq-datasource/pkg/rabbitmqclient"
+	amqp091 "github.com/rabbitmq/amqp091-go"
 	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
 	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+	"golang.org/x/sync/errgroup"
 )
 
 func (ds *RabbitMQDatasource) RunStream(ctx context.Context, req *backend.RunStreamRequest, sender *backend.StreamSender) error {
-	log.DefaultLogger.Info("Called RunStream method", "RabbitMQ Stream", ds.Client.ToString())
+	log.DefaultLogger.Info("Called RunStream method", "RabbitMQ Stream", ds.Client.ToString(), "path", req.Path)
 
-	framer := NewFramer()
+	query, err := ds.Queries.Subscribe(req.Path)
+	if err != nil {
+		return err
+	}
+	defer ds.Queries.Unsubscribe(req.Path)
+	var streamOptions *rabbitmqclient.StreamOptions
+	if !query.Queue.IsEnabled() {
+		if streamOptions, err = query.ToStreamOptions(ds.Client.GetStreamOptions()); err != nil {
+			return err
+		}
+	}
 
-	handleMessages := func(consumerContext stream.ConsumerContext, message *amqp.Message) {
-		log.DefaultLogger.Debug("Received message", "message", string(message.Data[0]))
+	subscriber := ds.Broadcasters.Subscribe(req.Path, sender, func(ctx context.Context, broadcaster *Broadcaster) error {
+		broadcastMessage := newMessageBroadcaster(ctx, broadcaster, &query.FramerOptions)
+		if query.Queue.IsEnabled() {
+			return ds.consumeQueue(ctx, query.Queue, broadcastMessage)
+		}
+		return ds.consumeStream(ctx, streamOptions, broadcastMessage)
+	})
+	defer subscriber.Close()
 
-		timestamped_msg := NewTimestampedMessage(message.Data[0])
-		frame, err := framer.ToFrame(timestamped_msg)
+	select {
+	case <-ctx.Done():
+		log.DefaultLogger.Debug("Stopped streaming - Context Canceled", "RabbitMQ Stream", ds.Client.ToString(), "path", req.Path)
+		return nil
+	case <-subscriber.Done():
+		return subscriber.Err()
+	}
+}
+
+// consumeStream runs a single consumer for all the subscribers of the broadcaster,
+// until the last subscriber is gone. The partitions of a super stream are consumed
+// together, and their messages are merged into the same frames with a partition field.
+func (ds *RabbitMQDatasource) consumeStream(ctx context.Context, streamOptions *rabbitmqclient.StreamOptions, broadcastMessage func(*TimestampedMessage, *data.Notice)) error {
+	if !streamOptions.SuperStream {
+		return ds.consumePartition(ctx, streamOptions, "", broadcastMessage)
+	}
+
+	if err := ds.connect(); err != nil {
+		return err
+	}
+	partitions, err := ds.Client.Partitions(streamOptions)
+	if err != nil {
+		return err
+	}
+	group, groupCtx := errgroup.WithContext(ctx)
+	for _, partition := range partitions {
+		partition := partition
+		group.Go(func() error {
+			return ds.consumePartition(groupCtx, streamOptions.PartitionOptions(partition), partition, broadcastMessage)
+		})
+	}
+	return group.Wait()
+}
+
+// newMessageBroadcaster returns a function that frames a message and broadcasts it to the subscribers.
+// The consumers of the partitions of a super stream call it concurrently, so the framer is locked.
+func newMessageBroadcaster(ctx context.Context, broadcaster *Broadcaster, framerOptions *FramerOptions) func(*TimestampedMessage, *data.Notice) {
+	framer := NewFramer(framerOptions)
+	var framerMutex sync.Mutex
+
+	return func(message *TimestampedMessage, notice *data.Notice) {
+		framerMutex.Lock()
+		frame, err := framer.ToFrame(message)
 		if err != nil {
-			log.DefaultLogger.Error("Error creating frame from message", "message", string(message.Data[0]), "error", err)
+			framerMutex.Unlock()
+			log.DefaultLogger.Error("Error creating frame from message", "message", string(message.Value), "error", err)
+			return
+		}
+		if notice != nil {
+			frame.AppendNotices(*notice)
+		}
+		frameJSON, err := data.FrameToJSON(frame, data.IncludeAll)
+		framerMutex.Unlock()
+		if err != nil {
+			log.DefaultLogger.Error("Error encoding frame", "frame", frame, "error", err)
 			return
 		}
 
 		select {
 		case <-ctx.Done():
-			log.DefaultLogger.Debug("Error sending frame because context canceled", "frame", frame, "error", err)
+			log.DefaultLogger.Debug("Error sending frame because context canceled", "frame", frame)
 		default:
-			err = sender.SendFrame(frame, data.IncludeAll)
-			if err != nil {
-				log.DefaultLogger.Error("Error sending frame", "frame", frame, "error", err)
-			}
+			broadcaster.Broadcast(frameJSON)
 		}
 	}
+}
+
+// consumeQueue consumes a classic or quorum queue for all the subscribers of the broadcaster, until the
+// last subscriber is gone. When the AMQP connection of the consumer is lost, the queue is consumed again
+// once the AMQP connection can be created again.
+func (ds *RabbitMQDatasource) consumeQueue(ctx context.Context, queueOptions *rabbitmqclient.QueueConsumerOptions, broadcastMessage func(*TimestampedMessage, *data.Notice)) error {
+	handleDelivery := func(delivery amqp091.Delivery) {
+		log.DefaultLogger.Debug("Received queue message", "message", string(delivery.Body))
+		broadcastMessage(NewTimestampedMessage(delivery.Body).WithCreationTime(delivery.Timestamp), nil)
+	}
+
+	log.DefaultLogger.Debug("Creating new queue consumer", "RabbitMQ Stream", ds.Client.ToString(), "queue", queueOptions.QueueName, "exchange", queueOptions.Exchange)
+	if err := ds.connect(); err != nil {
+		return err
+	}
+	subscription, err := ds.Client.ConsumeQueue(queueOptions, handleDelivery)
+	if err != nil {
+		return err
+	}
 
 	for {
-		log.DefaultLogger.Debug("Creating new consumer", "RabbitMQ Stream", ds.Client.ToString())
-		if !ds.Client.IsConnected() {
-			_, err := ds.Client.Connect()
-			if err != nil {
+		select {
+		case <-ctx.Done():
+			log.DefaultLogger.Debug("Stopped consuming the queue - no subscribers left", "RabbitMQ Stream", ds.Client.ToString())
+			return subscription.Close()
+		case <-subscription.NotifyClose():
+			log.DefaultLogger.Info(
+				"The queue consumer was closed by the RabbitMQ. Trying to consume it again...",
+				"RabbitMQ Stream", ds.Client.ToString(),
+			)
+			if subscription, err = ds.Client.ReconsumeQueue(ctx, queueOptions, handleDelivery); err != nil {
+				if ctx.Err() != nil {
+					log.DefaultLogger.Debug("Stopped consuming the queue again - no subscribers left", "RabbitMQ Stream", ds.Client.ToString())
+					return nil
+				}
 				return err
 			}
 		}
-		consumer, err := ds.Client.Consume(handleMessages)
-		if errors.Is(err, rabbitmqclient.ErrConsumerWasAlreadyCreated) {
-			return nil
+	}
+}
+
+// consumePartition consumes a stream, or a single partition of a super stream. When the consumer is
+// recreated after a reconnection, it resumes right after the last offset that was delivered to the subscribers.
+func (ds *RabbitMQDatasource) consumePartition(ctx context.Context, streamOptions *rabbitmqclient.StreamOptions, partition string, broadcastMessage func(*TimestampedMessage, *data.Notice)) error {
+	var lastDeliveredOffset atomic.Int64
+	lastDeliveredOffset.Store(-1)
+	var pendingNotice atomic.Pointer[data.Notice]
+
+	handleMessages := func(consumerContext stream.ConsumerContext, message *amqp.Message) {
+		lastDeliveredOffset.Store(consumerContext.Consumer.GetOffset())
+		if len(message.Data) == 0 {
+			return
+		}
+		log.DefaultLogger.Debug("Received message", "message", string(message.Data[0]))
+		creationTime, _ := getCreationTime(message)
+		broadcastMessage(NewTimestampedMessage(message.Data[0]).InPartition(partition).WithCreationTime(creationTime), pendingNotice.Swap(nil))
+	}
+
+	consumerStreamOptions := streamOptions
+	for {
+		log.DefaultLogger.Debug("Creating new consumer", "RabbitMQ Stream", ds.Client.ToString(), "StreamName", streamOptions.StreamName)
+		if err := ds.connect(); err != nil {
+			return err
+		}
+		subscription, err := ds.Client.Consume(consumerStreamOptions, handleMessages)
+		if err != nil {
+			return err
 		}
 
 		select {
 		case <-ctx.Done():
-			log.DefaultLogger.Debug("Stopped streaming - Context Canceled", "RabbitMQ Stream", ds.Client.ToString())
-			ds.Client.Dispose()
-			return nil
-		case <-consumer.NotifyClose():
+			log.DefaultLogger.Debug("Stopped consuming - no subscribers left", "RabbitMQ Stream", ds.Client.ToString(), "StreamName", streamOptions.StreamName)
+			return subscription.Close()
+		case <-subscription.NotifyClose():
 			log.DefaultLogger.Info(
 				"Something went wrong with the RabbitMQ. Trying to reconnect...",
 				"RabbitMQ Stream", ds.Client.ToString(),
 			)
-			ds.Client.Reconnect()
+			_ = subscription.Close()
+			if err := ds.Client.Reconnect(ctx); err != nil {
+				if ctx.Err() != nil {
+					log.DefaultLogger.Debug("Stopped reconnecting - no subscribers left", "RabbitMQ Stream", ds.Client.ToString())
+					return nil
+				}
+				return err
+			}
+		}
+
+		if offset := lastDeliveredOffset.Load(); offset >= 0 {
+			consumerStreamOptions = streamOptions.ResumeAfter(offset)
+			if notice := ds.getRetentionGapNotice(streamOptions, offset); notice != nil {
+				pendingNotice.Store(notice)
+			}
 		}
 	}
 }
 
-// SubscribeStream just returns an ok in this case, since we will always allow the user to successfully connect.
+// connect connects the client when it is not connected, the client serializes the concurrent connections.
+func (ds *RabbitMQDatasource) connect() error {
+	_, err := ds.Client.Connect()
+	return err
+}
+
+// getRetentionGapNotice returns a warning notice when messages after the last delivered offset
+// were removed by the retention of the stream while the consumer was down.
+func (ds *RabbitMQDatasource) getRetentionGapNotice(streamOptions *rabbitmqclient.StreamOptions, lastDeliveredOffset int64) *data.Notice {
+	firstOffset, err := ds.Client.FirstOffset(streamOptions)
+	if err != nil || firstOffset <= lastDeliveredOffset+1 {
+		return nil
+	}
+	lostMessages := firstOffset - lastDeliveredOffset - 1
+	log.DefaultLogger.Warn("Messages were removed by the retention of the stream while reconnecting", "StreamName", streamOptions.StreamName, "lost", lostMessages)
+	return &data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text: fmt.Sprintf("%d messages of the stream %s (offsets %d to %d) were removed by its retention while reconnecting to the RabbitMQ",
+			lostMessages, streamOptions.StreamName, lastDeliveredOffset+1, firstOffset-1),
+	}
+}
+
+// SubscribeStream returns an ok for every channel path that was stored by a query, since we will always allow the user to successfully connect.
 // Permissions verifications could be done here. Check backend.StreamHandler docs for more details.
 func (ds *RabbitMQDatasource) SubscribeStream(_ context.Context, req *backend.SubscribeStreamRequest) (*backend.SubscribeStreamResponse, error) {
-	log.DefaultLogger.Info("Called SubscribeStream method", "RabbitMQ Stream", ds.Client.ToString())
+	log.DefaultLogger.Info("Called SubscribeStream method", "RabbitMQ Stream", ds.Client.ToString(), "path", req.Path)
+	if _, err := ds.Queries.Get(req.Path); err != nil {
+		log.DefaultLogger.Error("Invalid channel path", "path", req.Path, "error", err)
+		return &backend.SubscribeStreamResponse{
+			Status: backend.SubscribeStreamStatusNotFound,
+		}, nil
+	}
 	return &backend.SubscribeStreamResponse{
 		Status: backend.SubscribeStreamStatusOK,
 	}, nil
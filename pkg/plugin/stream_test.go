@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/maormil/rabbitmq-datasource/pkg/rabbitmqclient"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+// fakeClient answers the calls of the datasource without a broker, the calls that are not faked panic.
+type fakeClient struct {
+	rabbitmqclient.Client
+	streamOptions  *rabbitmqclient.StreamOptions
+	messages       []*amqp.Message
+	firstOffset    int64
+	firstOffsetErr error
+}
+
+func (client *fakeClient) GetStreamOptions() *rabbitmqclient.StreamOptions {
+	return client.streamOptions
+}
+
+func (client *fakeClient) Partitions(streamOptions *rabbitmqclient.StreamOptions) ([]string, error) {
+	return []string{streamOptions.StreamName}, nil
+}
+
+func (client *fakeClient) Read(_ context.Context, _ *rabbitmqclient.StreamOptions, _ stream.OffsetSpecification, readHandler rabbitmqclient.ReadHandler) error {
+	consumerContext := stream.ConsumerContext{Consumer: &stream.Consumer{}}
+	for _, message := range client.messages {
+		if !readHandler(consumerContext, message) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (client *fakeClient) FirstOffset(*rabbitmqclient.StreamOptions) (int64, error) {
+	return client.firstOffset, client.firstOffsetErr
+}
+
+func TestRetentionGapNotice(t *testing.T) {
+	tests := []struct {
+		name                string
+		client              *fakeClient
+		lastDeliveredOffset int64
+		expectedText        string
+	}{
+		{
+			name:                "nothing removed",
+			client:              &fakeClient{firstOffset: 0},
+			lastDeliveredOffset: 10,
+		},
+		{
+			name:                "first offset right after the last delivered offset",
+			client:              &fakeClient{firstOffset: 11},
+			lastDeliveredOffset: 10,
+		},
+		{
+			name:                "resume point removed by the retention",
+			client:              &fakeClient{firstOffset: 15},
+			lastDeliveredOffset: 10,
+			expectedText:        "4 messages of the stream rabbitmq.stream (offsets 11 to 14)",
+		},
+		{
+			name:                "removed after the first offset",
+			client:              &fakeClient{firstOffset: 5},
+			lastDeliveredOffset: 0,
+			expectedText:        "4 messages of the stream rabbitmq.stream (offsets 1 to 4)",
+		},
+		{
+			name:                "empty stream",
+			client:              &fakeClient{firstOffsetErr: stream.OffsetNotFoundError},
+			lastDeliveredOffset: 10,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ds := &RabbitMQDatasource{Client: test.client}
+			notice := ds.getRetentionGapNotice(&rabbitmqclient.StreamOptions{StreamName: "rabbitmq.stream"}, test.lastDeliveredOffset)
+			if test.expectedText == "" {
+				if notice != nil {
+					t.Errorf("unexpected notice: %s", notice.Text)
+				}
+				return
+			}
+			if notice == nil || !strings.HasPrefix(notice.Text, test.expectedText) {
+				t.Errorf("unexpected notice: got %v, want %q", notice, test.expectedText)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/PaesslerAG/gval"
+	"github.com/PaesslerAG/jsonpath"
+)
+
+const (
+	// TIMESTAMP_SOURCE_CONSUME is the time the plugin consumed the message
+	TIMESTAMP_SOURCE_CONSUME = "consume"
+	// TIMESTAMP_SOURCE_CREATION_TIME is the creation-time property of an AMQP 1.0 stream message,
+	// or the timestamp property of an AMQP 0-9-1 queue message
+	TIMESTAMP_SOURCE_CREATION_TIME = "creationTime"
+	// TIMESTAMP_SOURCE_FIELD is a field of the payload of the message
+	TIMESTAMP_SOURCE_FIELD = "field"
+
+	TIMESTAMP_LAYOUT_RFC3339  = "rfc3339"
+	TIMESTAMP_LAYOUT_EPOCH_S  = "epochSeconds"
+	TIMESTAMP_LAYOUT_EPOCH_MS = "epochMillis"
+	TIMESTAMP_LAYOUT_EPOCH_US = "epochMicros"
+	TIMESTAMP_LAYOUT_EPOCH_NS = "epochNanos"
+)
+
+var epochUnits = map[string]time.Duration{
+	TIMESTAMP_LAYOUT_EPOCH_S:  time.Second,
+	TIMESTAMP_LAYOUT_EPOCH_MS: time.Millisecond,
+	TIMESTAMP_LAYOUT_EPOCH_US: time.Microsecond,
+	TIMESTAMP_LAYOUT_EPOCH_NS: time.Nanosecond,
+}
+
+// TimestampOptions chooses where the time of the rows comes from. The rows of the messages without
+// a timestamp in the source fall back to the time the message was consumed.
+// The timestamp of the chunks of the stream is not a source, the stream client doesn't expose it to the consumers.
+type TimestampOptions struct {
+	Source string `json:"source,omitempty"`
+	// Field is a JSONPath expression of the timestamp in the payload, which is evaluated on every exploded row
+	Field string `json:"field,omitempty"`
+	// Layout is the format of the timestamp field, a RFC3339 string or an epoch number (or numeric string)
+	Layout string `json:"layout,omitempty"`
+}
+
+func (timestampOptions *TimestampOptions) Validate() error {
+	if timestampOptions == nil {
+		return nil
+	}
+	switch timestampOptions.Source {
+	case "", TIMESTAMP_SOURCE_CONSUME, TIMESTAMP_SOURCE_CREATION_TIME:
+	case TIMESTAMP_SOURCE_FIELD:
+		if timestampOptions.Field == "" {
+			return fmt.Errorf("invalid timestamp options: the field of the timestamp is missing")
+		}
+		if _, err := jsonpath.New(timestampOptions.Field); err != nil {
+			return fmt.Errorf("invalid JSONPath expression %s of the timestamp field: %w", timestampOptions.Field, err)
+		}
+	default:
+		return fmt.Errorf("invalid timestamp options: unknown source %q, the source must be one of %s, %s or %s",
+			timestampOptions.Source, TIMESTAMP_SOURCE_CONSUME, TIMESTAMP_SOURCE_CREATION_TIME, TIMESTAMP_SOURCE_FIELD)
+	}
+	if _, isEpoch := epochUnits[timestampOptions.Layout]; !isEpoch && timestampOptions.Layout != "" && timestampOptions.Layout != TIMESTAMP_LAYOUT_RFC3339 {
+		return fmt.Errorf("invalid timestamp options: unknown layout %q, the layout must be one of %s, %s, %s, %s or %s",
+			timestampOptions.Layout, TIMESTAMP_LAYOUT_RFC3339, TIMESTAMP_LAYOUT_EPOCH_S, TIMESTAMP_LAYOUT_EPOCH_MS, TIMESTAMP_LAYOUT_EPOCH_US, TIMESTAMP_LAYOUT_EPOCH_NS)
+	}
+	return nil
+}
+
+// isConsumeTime tells whether the rows keep the time the message was consumed.
+func (timestampOptions *TimestampOptions) isConsumeTime() bool {
+	return timestampOptions == nil || timestampOptions.Source == "" || timestampOptions.Source == TIMESTAMP_SOURCE_CONSUME
+}
+
+// isFieldTime tells whether the rows take their time from a field of the payload.
+func (timestampOptions *TimestampOptions) isFieldTime() bool {
+	return timestampOptions != nil && timestampOptions.Source == TIMESTAMP_SOURCE_FIELD
+}
+
+// timestamper picks the time of the rows from the source of the timestamp options.
+type timestamper struct {
+	source string
+	field  gval.Evaluable
+	layout string
+}
+
+// newTimestamper compiles the timestamp field, it was already validated with the query.
+func newTimestamper(timestampOptions *TimestampOptions) *timestamper {
+	if timestampOptions.isConsumeTime() {
+		return nil
+	}
+	stamper := &timestamper{source: timestampOptions.Source, layout: timestampOptions.Layout}
+	if timestampOptions.Source == TIMESTAMP_SOURCE_FIELD {
+		stamper.field, _ = jsonpath.New(timestampOptions.Field)
+	}
+	return stamper
+}
+
+// timestamp returns the time of a row of the message, or the consume time of the message when the source has no timestamp.
+func (stamper *timestamper) timestamp(message *TimestampedMessage, row []byte) (time.Time, error) {
+	if stamper == nil {
+		return message.Timestamp, nil
+	}
+	switch stamper.source {
+	case TIMESTAMP_SOURCE_CREATION_TIME:
+		if !message.CreationTime.IsZero() {
+			return message.CreationTime, nil
+		}
+	case TIMESTAMP_SOURCE_FIELD:
+		// the numbers are decoded as json.Number, the epoch nanoseconds don't fit in a float64
+		decoder := json.NewDecoder(bytes.NewReader(row))
+		decoder.UseNumber()
+		var document interface{}
+		if err := decoder.Decode(&document); err != nil {
+			return message.Timestamp, err
+		}
+		value, err := stamper.field(context.Background(), document)
+		if err != nil || value == nil {
+			return message.Timestamp, nil
+		}
+		timestamp, err := parseTimestamp(value, stamper.layout)
+		if err != nil {
+			return message.Timestamp, err
+		}
+		return timestamp, nil
+	}
+	return message.Timestamp, nil
+}
+
+// parseTimestamp converts the value of the timestamp field with its layout. Without a layout, the strings
+// are RFC3339 timestamps and the numbers are epoch milliseconds, like the fields of type time.
+func parseTimestamp(value interface{}, layout string) (time.Time, error) {
+	number, isNumber := value.(json.Number)
+	switch layout {
+	case "":
+		if isNumber {
+			return toTime(number.String())
+		}
+		return toTime(value)
+	case TIMESTAMP_LAYOUT_RFC3339:
+		if text, isString := value.(string); isString {
+			return time.Parse(time.RFC3339Nano, text)
+		}
+		return time.Time{}, fmt.Errorf("can't parse %v as a RFC3339 timestamp", value)
+	}
+
+	text, isString := value.(string)
+	if isNumber {
+		text, isString = number.String(), true
+	}
+	if !isString {
+		return time.Time{}, fmt.Errorf("can't parse %T as an epoch timestamp", value)
+	}
+	unit := epochUnits[layout]
+	if epoch, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return time.Unix(0, epoch*int64(unit)), nil
+	}
+	epoch, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("can't parse %q as an epoch timestamp", text)
+	}
+	whole, fraction := math.Modf(epoch)
+	return time.Unix(0, int64(whole)*int64(unit)+int64(fraction*float64(unit))), nil
+}
@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+var timestampTime = time.Date(2024, 1, 1, 12, 30, 15, 250_000_000, time.UTC)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        interface{}
+		layout       string
+		expectedTime time.Time
+		expectError  bool
+	}{
+		{name: "default string", value: "2024-01-01T12:30:15.25Z", expectedTime: timestampTime},
+		{name: "default number", value: json.Number("1704112215250"), expectedTime: timestampTime},
+		{name: "default numeric string", value: "1704112215250", expectedTime: timestampTime},
+		{name: "rfc3339", value: "2024-01-01T14:30:15.25+02:00", layout: TIMESTAMP_LAYOUT_RFC3339, expectedTime: timestampTime},
+		{name: "rfc3339 number", value: json.Number("1704112215250"), layout: TIMESTAMP_LAYOUT_RFC3339, expectError: true},
+		{name: "epoch seconds", value: json.Number("1704112215.25"), layout: TIMESTAMP_LAYOUT_EPOCH_S, expectedTime: timestampTime},
+		{name: "epoch milliseconds", value: json.Number("1704112215250"), layout: TIMESTAMP_LAYOUT_EPOCH_MS, expectedTime: timestampTime},
+		{name: "epoch microseconds", value: "1704112215250000", layout: TIMESTAMP_LAYOUT_EPOCH_US, expectedTime: timestampTime},
+		// the epoch nanoseconds don't fit in a float64
+		{name: "epoch nanoseconds", value: json.Number("1704112215250000001"), layout: TIMESTAMP_LAYOUT_EPOCH_NS, expectedTime: timestampTime.Add(time.Nanosecond)},
+		{name: "epoch text", value: "yesterday", layout: TIMESTAMP_LAYOUT_EPOCH_MS, expectError: true},
+		{name: "epoch boolean", value: true, layout: TIMESTAMP_LAYOUT_EPOCH_MS, expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			timestamp, err := parseTimestamp(test.value, test.layout)
+			if (err != nil) != test.expectError {
+				t.Fatalf("unexpected error: got %v, want an error: %v", err, test.expectError)
+			}
+			if !test.expectError && !timestamp.Equal(test.expectedTime) {
+				t.Errorf("unexpected timestamp: got %v, want %v", timestamp.UTC(), test.expectedTime)
+			}
+		})
+	}
+}
+
+func TestTimestamper(t *testing.T) {
+	consumeTime := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	creationTime := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name             string
+		timestampOptions *TimestampOptions
+		row              string
+		expectedTime     time.Time
+	}{
+		{name: "consume time", row: `{"time": 1704112215250}`, expectedTime: consumeTime},
+		{
+			name:             "creation time",
+			timestampOptions: &TimestampOptions{Source: TIMESTAMP_SOURCE_CREATION_TIME},
+			row:              `{"time": 1704112215250}`,
+			expectedTime:     creationTime,
+		},
+		{
+			name:             "field",
+			timestampOptions: &TimestampOptions{Source: TIMESTAMP_SOURCE_FIELD, Field: "$.time", Layout: TIMESTAMP_LAYOUT_EPOCH_MS},
+			row:              `{"time": 1704112215250}`,
+			expectedTime:     timestampTime,
+		},
+		{
+			name:             "missing field",
+			timestampOptions: &TimestampOptions{Source: TIMESTAMP_SOURCE_FIELD, Field: "$.time"},
+			row:              `{"value": 1}`,
+			expectedTime:     consumeTime,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.timestampOptions.Validate(); err != nil {
+				t.Fatalf("invalid timestamp options: %v", err)
+			}
+			message := NewTimestampedMessageAt([]byte(test.row), consumeTime).WithCreationTime(creationTime)
+			timestamp, err := newTimestamper(test.timestampOptions).timestamp(message, message.Value)
+			if err != nil {
+				t.Fatalf("timestamp returned an error: %v", err)
+			}
+			if !timestamp.Equal(test.expectedTime) {
+				t.Errorf("unexpected timestamp: got %v, want %v", timestamp.UTC(), test.expectedTime)
+			}
+		})
+	}
+}
+
+func TestDropRowsOutside(t *testing.T) {
+	framer := NewFramer(&FramerOptions{
+		Explode:   &ExplodeOptions{Path: "$.readings"},
+		Timestamp: &TimestampOptions{Source: TIMESTAMP_SOURCE_FIELD, Field: "$.time", Layout: TIMESTAMP_LAYOUT_EPOCH_S},
+	})
+	timeRange := backend.TimeRange{From: time.Unix(100, 0), To: time.Unix(200, 0)}
+	messages := []string{
+		`{"readings": [{"time": 50, "value": 1}, {"time": 100, "value": 2}]}`,
+		`{"readings": [{"time": 150, "value": 3}, {"time": 250, "value": 4}, {"time": 200, "value": 5}]}`,
+	}
+	for _, message := range messages {
+		firstRow := framer.Rows()
+		if err := framer.AppendMessage(NewTimestampedMessage([]byte(message))); err != nil {
+			t.Fatalf("AppendMessage returned an error: %v", err)
+		}
+		framer.DropRowsOutside(timeRange, firstRow)
+	}
+
+	frame := framer.Frame()
+	field, _ := frame.FieldByName("value")
+	if field == nil {
+		t.Fatalf("the value field is missing")
+	}
+	values := fieldValues(field)
+	expectedValues := []interface{}{float64(2), float64(3), float64(5)}
+	if !reflect.DeepEqual(values, expectedValues) {
+		t.Errorf("unexpected values: got %v, want %v", values, expectedValues)
+	}
+}
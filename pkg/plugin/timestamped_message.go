@@ -5,6 +5,10 @@ import "time"
 type TimestampedMessage struct {
 	Timestamp time.Time
 	Value     []byte
+	// CreationTime is the time the producer created the message, it is zero when the message has none
+	CreationTime time.Time
+	// Partition is the partition of the super stream the message was consumed from
+	Partition string
 }
 
 func NewTimestampedMessage(value []byte) *TimestampedMessage {
@@ -13,3 +17,22 @@ func NewTimestampedMessage(value []byte) *TimestampedMessage {
 		Value:     value,
 	}
 }
+
+func NewTimestampedMessageAt(value []byte, timestamp time.Time) *TimestampedMessage {
+	return &TimestampedMessage{
+		Timestamp: timestamp,
+		Value:     value,
+	}
+}
+
+// InPartition sets the partition of the super stream the message was consumed from.
+func (message *TimestampedMessage) InPartition(partition string) *TimestampedMessage {
+	message.Partition = partition
+	return message
+}
+
+// WithCreationTime sets the time the producer created the message.
+func (message *TimestampedMessage) WithCreationTime(creationTime time.Time) *TimestampedMessage {
+	message.CreationTime = creationTime
+	return message
+}
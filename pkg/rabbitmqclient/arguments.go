@@ -0,0 +1,46 @@
+package rabbitmqclient
+
+import (
+	"math"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// toTable converts the arguments that were parsed from the JSON data into AMQP arguments. The JSON
+// numbers are parsed as floats, but RabbitMQ rejects floats for the integer arguments (like x-message-ttl),
+// so every whole number is sent as an integer.
+func toTable(arguments map[string]interface{}) amqp.Table {
+	table := amqp.Table{}
+	for key, value := range arguments {
+		table[key] = toTableValue(value)
+	}
+	return table
+}
+
+func toTableValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case float64:
+		if typedValue == math.Trunc(typedValue) && math.Abs(typedValue) < math.MaxInt64 {
+			return int64(typedValue)
+		}
+		return typedValue
+	case map[string]interface{}:
+		return toTable(typedValue)
+	case []interface{}:
+		values := make([]interface{}, len(typedValue))
+		for index, item := range typedValue {
+			values[index] = toTableValue(item)
+		}
+		return values
+	default:
+		return value
+	}
+}
+
+// toTableOrNil keeps the nil arguments when no argument was set.
+func toTableOrNil(arguments map[string]interface{}) amqp.Table {
+	if len(arguments) == 0 {
+		return nil
+	}
+	return toTable(arguments)
+}
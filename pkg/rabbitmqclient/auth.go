@@ -0,0 +1,144 @@
+package rabbitmqclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	amqp "github.com/rabbitmq/amqp091-go"
+	stream "github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	AUTH_MODE_PLAIN    = "plain"
+	AUTH_MODE_EXTERNAL = "external"
+	AUTH_MODE_OAUTH2   = "oauth2"
+)
+
+// oauth2TokenRefreshMargin refreshes the token (and the connections that use it) before the
+// broker closes the connections of an expired token.
+const oauth2TokenRefreshMargin time.Duration = 60 * time.Second
+
+// oauth2MinTokenRefreshDelay keeps the refreshes of the tokens that are already expired,
+// or about to, from reconnecting in a tight loop.
+const oauth2MinTokenRefreshDelay time.Duration = 5 * time.Second
+
+func (options *RabbitMQStreamOptions) getAuthMode() string {
+	if options.AuthMode == "" {
+		return AUTH_MODE_PLAIN
+	}
+	return options.AuthMode
+}
+
+func (options *RabbitMQStreamOptions) ValidateAuth() error {
+	switch options.getAuthMode() {
+	case AUTH_MODE_PLAIN:
+		return nil
+	case AUTH_MODE_EXTERNAL:
+		if !options.IsTLS || options.TLSClientCert == "" {
+			return fmt.Errorf("the EXTERNAL auth mode requires a TLS connection with a TLS client certificate")
+		}
+		return nil
+	case AUTH_MODE_OAUTH2:
+		if options.OAuth2TokenURL == "" || options.OAuth2ClientID == "" {
+			return fmt.Errorf("the OAuth2 auth mode requires a token URL and a client ID")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown auth mode %q: the auth mode must be one of %s, %s or %s",
+			options.AuthMode, AUTH_MODE_PLAIN, AUTH_MODE_EXTERNAL, AUTH_MODE_OAUTH2)
+	}
+}
+
+func (options *RabbitMQStreamOptions) getSaslMechanism() string {
+	if options.getAuthMode() == AUTH_MODE_EXTERNAL {
+		return stream.SaslConfigurationExternal
+	}
+	return stream.SaslConfigurationPlain
+}
+
+// getCredentials returns the user and password of the PLAIN mechanism, which is a fresh
+// access token as password in the OAuth2 auth mode, and nothing in the EXTERNAL auth mode.
+func (client *RabbitMQStreamClient) getCredentials() (string, string, error) {
+	options := client.RabbitMQOptions
+	switch options.getAuthMode() {
+	case AUTH_MODE_EXTERNAL:
+		return "", "", nil
+	case AUTH_MODE_OAUTH2:
+		token, err := client.getTokenSource().Token()
+		if err != nil {
+			return "", "", failOnError(err, "Failed to get an OAuth2 access token")
+		}
+		client.scheduleTokenRefresh(token.Expiry)
+		return options.OAuth2ClientID, token.AccessToken, nil
+	default:
+		return options.User, options.Password, nil
+	}
+}
+
+func (client *RabbitMQStreamClient) getAmqpAuthentication(user string, password string) []amqp.Authentication {
+	if client.RabbitMQOptions.getAuthMode() == AUTH_MODE_EXTERNAL {
+		return []amqp.Authentication{&amqp.ExternalAuth{}}
+	}
+	return []amqp.Authentication{&amqp.PlainAuth{Username: user, Password: password}}
+}
+
+// getTokenSource returns the client credentials token source, it caches the token
+// and fetches a new one once the cached token is about to expire.
+func (client *RabbitMQStreamClient) getTokenSource() oauth2.TokenSource {
+	client.tokenMutex.Lock()
+	defer client.tokenMutex.Unlock()
+	if client.tokenSource == nil {
+		options := client.RabbitMQOptions
+		config := &clientcredentials.Config{
+			ClientID:     options.OAuth2ClientID,
+			ClientSecret: options.OAuth2ClientSecret,
+			TokenURL:     options.OAuth2TokenURL,
+			Scopes:       strings.Fields(options.OAuth2Scopes),
+		}
+		client.tokenSource = oauth2.ReuseTokenSourceWithExpiry(nil, config.TokenSource(context.Background()), oauth2TokenRefreshMargin)
+	}
+	return client.tokenSource
+}
+
+// getTokenRefreshDelay returns the delay before the refresh of a token that expires after the lifetime. A token
+// that lives less than the refresh margin is refreshed halfway through its lifetime instead of right away.
+func getTokenRefreshDelay(lifetime time.Duration) time.Duration {
+	delay := lifetime - oauth2TokenRefreshMargin
+	if delay < lifetime/2 {
+		delay = lifetime / 2
+	}
+	if delay < oauth2MinTokenRefreshDelay {
+		delay = oauth2MinTokenRefreshDelay
+	}
+	return delay
+}
+
+// scheduleTokenRefresh recreates the connections with a new token right before the token
+// expires. The consumers are resumed by the reconnection, after their last delivered offset.
+func (client *RabbitMQStreamClient) scheduleTokenRefresh(expiry time.Time) {
+	if expiry.IsZero() {
+		return
+	}
+	client.tokenMutex.Lock()
+	defer client.tokenMutex.Unlock()
+	if client.tokenRefreshTimer != nil {
+		client.tokenRefreshTimer.Stop()
+	}
+	client.tokenRefreshTimer = time.AfterFunc(getTokenRefreshDelay(time.Until(expiry)), func() {
+		if client.isDisposed() {
+			return
+		}
+		log.DefaultLogger.Info("OAuth2 access token is about to expire, reconnecting with a new token", "RabbitMQ Stream", client.ToString())
+		if err := client.closeEnvironment(); err != nil {
+			log.DefaultLogger.Debug("Failed to close the RabbitMQ environment of the expiring token", "error", err)
+		}
+		if err := client.Reconnect(context.Background()); err != nil {
+			log.DefaultLogger.Error("Failed to reconnect with a new OAuth2 access token", "error", err)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package rabbitmqclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	stream "github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+func TestValidateAuth(t *testing.T) {
+	tests := []struct {
+		name                  string
+		options               *RabbitMQStreamOptions
+		expectError           bool
+		expectedSaslMechanism string
+	}{
+		{
+			name:                  "plain by default",
+			options:               &RabbitMQStreamOptions{},
+			expectedSaslMechanism: stream.SaslConfigurationPlain,
+		},
+		{
+			name:                  "external",
+			options:               &RabbitMQStreamOptions{AuthMode: AUTH_MODE_EXTERNAL, IsTLS: true, TLSClientCert: "certificate"},
+			expectedSaslMechanism: stream.SaslConfigurationExternal,
+		},
+		{
+			name:        "external without a client certificate",
+			options:     &RabbitMQStreamOptions{AuthMode: AUTH_MODE_EXTERNAL, IsTLS: true},
+			expectError: true,
+		},
+		{
+			name:                  "oauth2",
+			options:               &RabbitMQStreamOptions{AuthMode: AUTH_MODE_OAUTH2, OAuth2TokenURL: "https://idp/token", OAuth2ClientID: "grafana"},
+			expectedSaslMechanism: stream.SaslConfigurationPlain,
+		},
+		{
+			name:        "oauth2 without a client ID",
+			options:     &RabbitMQStreamOptions{AuthMode: AUTH_MODE_OAUTH2, OAuth2TokenURL: "https://idp/token"},
+			expectError: true,
+		},
+		{
+			name:        "unknown auth mode",
+			options:     &RabbitMQStreamOptions{AuthMode: "kerberos"},
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.options.ValidateAuth()
+			if (err != nil) != test.expectError {
+				t.Fatalf("unexpected validation error: got %v, want an error: %v", err, test.expectError)
+			}
+			if test.expectError {
+				return
+			}
+			if mechanism := test.options.getSaslMechanism(); mechanism != test.expectedSaslMechanism {
+				t.Errorf("unexpected SASL mechanism: got %s, want %s", mechanism, test.expectedSaslMechanism)
+			}
+		})
+	}
+}
+
+func TestGetCredentialsWithOAuth2(t *testing.T) {
+	tokenRequests := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		tokenRequests += 1
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"access_token": "access-token", "token_type": "bearer", "expires_in": 3600}`))
+	}))
+	defer tokenServer.Close()
+
+	client := NewRabbitMQStreamClient().SetRabbitMQOptions(&RabbitMQStreamOptions{
+		AuthMode:           AUTH_MODE_OAUTH2,
+		OAuth2TokenURL:     tokenServer.URL,
+		OAuth2ClientID:     "grafana",
+		OAuth2ClientSecret: "secret",
+	})
+	defer client.Dispose()
+
+	for attempt := 0; attempt < 2; attempt += 1 {
+		user, password, err := client.getCredentials()
+		if err != nil {
+			t.Fatalf("getCredentials returned an error: %v", err)
+		}
+		if user != "grafana" || password != "access-token" {
+			t.Errorf("unexpected credentials: got %s:%s, want grafana:access-token", user, password)
+		}
+	}
+	// the token is cached until it is about to expire
+	if tokenRequests != 1 {
+		t.Errorf("unexpected number of token requests: got %d, want 1", tokenRequests)
+	}
+}
+
+func TestGetTokenRefreshDelay(t *testing.T) {
+	tests := []struct {
+		name          string
+		lifetime      time.Duration
+		expectedDelay time.Duration
+	}{
+		{name: "long lived token", lifetime: time.Hour, expectedDelay: time.Hour - oauth2TokenRefreshMargin},
+		{name: "token shorter than twice the margin", lifetime: 90 * time.Second, expectedDelay: 45 * time.Second},
+		{name: "token shorter than the margin", lifetime: 30 * time.Second, expectedDelay: 15 * time.Second},
+		{name: "token about to expire", lifetime: time.Second, expectedDelay: oauth2MinTokenRefreshDelay},
+		{name: "expired token", lifetime: -time.Minute, expectedDelay: oauth2MinTokenRefreshDelay},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if delay := getTokenRefreshDelay(test.lifetime); delay != test.expectedDelay {
+				t.Errorf("unexpected refresh delay: got %v, want %v", delay, test.expectedDelay)
+			}
+		})
+	}
+}
@@ -2,13 +2,11 @@ package rabbitmqclient
 
 import (
 	"fmt"
-
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type Binding interface {
-	CreateBinding(*amqp.Channel) error
-	DisposeBinding(*amqp.Channel) error
+	CreateBinding(TopologyChannel) error
+	DisposeBinding(TopologyChannel) error
 }
 
 type BindingOptions struct {
@@ -18,9 +16,11 @@ type BindingOptions struct {
 	NoWait               bool   `json:"noWait"`
 	IsQueueBinding       bool   `json:"isQueueBinding"`
 	ShouldDisposeBinding bool   `json:"shouldDisposeBinding"`
+	// Arguments match the headers of the messages in a binding of a headers exchange, like x-match
+	Arguments map[string]interface{} `json:"arguments"`
 }
 
-func (bindingOptions *BindingOptions) CreateBinding(ch *amqp.Channel) error {
+func (bindingOptions *BindingOptions) CreateBinding(ch TopologyChannel) error {
 	var err error = nil
 	receiverType := "Queue"
 	if bindingOptions.IsQueueBinding {
@@ -29,7 +29,7 @@ func (bindingOptions *BindingOptions) CreateBinding(ch *amqp.Channel) error {
 			bindingOptions.RoutingKey,
 			bindingOptions.SenderName,
 			bindingOptions.NoWait,
-			nil, // arguments - not supported at the moment
+			toTableOrNil(bindingOptions.Arguments),
 		)
 	} else {
 		err = ch.ExchangeBind(
@@ -37,7 +37,7 @@ func (bindingOptions *BindingOptions) CreateBinding(ch *amqp.Channel) error {
 			bindingOptions.RoutingKey,
 			bindingOptions.SenderName,
 			bindingOptions.NoWait,
-			nil, // arguments - not supported at the moment
+			toTableOrNil(bindingOptions.Arguments),
 		)
 		receiverType = "Exchange"
 	}
@@ -52,7 +52,7 @@ func (bindingOptions *BindingOptions) CreateBinding(ch *amqp.Channel) error {
 	)
 }
 
-func (bindingOptions *BindingOptions) DisposeBinding(ch *amqp.Channel) error {
+func (bindingOptions *BindingOptions) DisposeBinding(ch TopologyChannel) error {
 	if !bindingOptions.ShouldDisposeBinding {
 		return nil
 	}
@@ -63,15 +63,15 @@ func (bindingOptions *BindingOptions) DisposeBinding(ch *amqp.Channel) error {
 			bindingOptions.ReceiverName,
 			bindingOptions.RoutingKey,
 			bindingOptions.SenderName,
-			nil, // arguments - not supported at the moment
+			toTableOrNil(bindingOptions.Arguments),
 		)
 	} else {
-		err = ch.ExchangeBind(
+		err = ch.ExchangeUnbind(
 			bindingOptions.ReceiverName,
 			bindingOptions.RoutingKey,
 			bindingOptions.SenderName,
 			bindingOptions.NoWait,
-			nil, // arguments - not supported at the moment
+			toTableOrNil(bindingOptions.Arguments),
 		)
 		receiverType = "Exchange"
 	}
@@ -0,0 +1,50 @@
+package rabbitmqclient
+
+import (
+	"errors"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+// TopologyChannel is the part of the AMQP channel that declares and deletes the topology (exchanges,
+// queues and bindings), so the topology can be created and disposed without a broker. *amqp.Channel implements it.
+type TopologyChannel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	ExchangeDeclarePassive(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	ExchangeDelete(name string, ifUnused, noWait bool) error
+	ExchangeBind(destination, key, source string, noWait bool, args amqp.Table) error
+	ExchangeUnbind(destination, key, source string, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueDeclarePassive(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueDelete(name string, ifUnused, ifEmpty, noWait bool) (int, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	QueueUnbind(name, key, exchange string, args amqp.Table) error
+	Close() error
+}
+
+var _ TopologyChannel = (*amqp.Channel)(nil)
+
+// StreamTopology is the part of the stream environment that checks and declares the streams and the
+// super streams, so they can be reconciled without a broker. *stream.Environment implements it.
+type StreamTopology interface {
+	StreamExists(streamName string) (bool, error)
+	QueryPartitions(superStreamName string) ([]string, error)
+	DeclareStream(streamName string, options *stream.StreamOptions) error
+	DeclareSuperStream(superStreamName string, options stream.SuperStreamOptions) error
+}
+
+var _ StreamTopology = (*stream.Environment)(nil)
+
+// checkExists turns the result of a passive declare into whether the object exists. The broker
+// answers a passive declare of a missing object with a not found error, which also closes the channel.
+func checkExists(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+		return false, nil
+	}
+	return false, err
+}
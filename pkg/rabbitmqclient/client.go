@@ -1,32 +1,64 @@
 package rabbitmqclient
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	amqp "github.com/rabbitmq/amqp091-go"
 	stream "github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+	"golang.org/x/oauth2"
 )
 
+// the defaults of amqp.Dial, which are not applied by amqp.DialConfig
+const amqpHeartbeat time.Duration = 10 * time.Second
+const amqpLocale = "en_US"
+
 type Client interface {
 	IsConnected() bool
 	Connect() (Client, error)
-	Reconnect() Client
-	Consume(stream.MessagesHandler) (*stream.Consumer, error)
+	Reconnect(context.Context) error
+	GetConnectionState() ConnectionState
+	GetStreamOptions() *StreamOptions
+	Consume(*StreamOptions, stream.MessagesHandler) (*ConsumerSubscription, error)
+	Read(context.Context, *StreamOptions, stream.OffsetSpecification, ReadHandler) error
+	OffsetAt(context.Context, *StreamOptions, time.Time) (int64, error)
+	FirstOffset(*StreamOptions) (int64, error)
+	Partitions(*StreamOptions) ([]string, error)
+	ReconcileTopology(dryRun bool) (*TopologyDiff, error)
+	ConsumeQueue(*QueueConsumerOptions, QueueMessageHandler) (*QueueSubscription, error)
+	ReconsumeQueue(context.Context, *QueueConsumerOptions, QueueMessageHandler) (*QueueSubscription, error)
 	Dispose()
 	ToString() string
 }
 
 type RabbitMQStreamOptions struct {
 	Host                  string             `json:"host"`
+	Endpoints             []string           `json:"endpoints"`
+	LoadBalancerMode      *bool              `json:"loadBalancerMode,omitempty"`
 	StreamPort            int                `json:"streamPort"`
 	AmqpPort              int                `json:"amqpPort"`
 	VHost                 string             `json:"vHost"`
+	AuthMode              string             `json:"authMode"`
 	User                  string             `json:"username"`
 	Password              string             `json:"password"`
+	OAuth2TokenURL        string             `json:"oauth2TokenUrl"`
+	OAuth2ClientID        string             `json:"oauth2ClientId"`
+	OAuth2ClientSecret    string             `json:"oauth2ClientSecret"`
+	OAuth2Scopes          string             `json:"oauth2Scopes"`
 	IsTLS                 bool               `json:"tlsConnection"`
-	TLSConfig             bool               `json:"TLSConfig"`
+	TLSServerName         string             `json:"tlsServerName"`
+	TLSSkipVerify         bool               `json:"tlsSkipVerify"`
+	TLSCACert             string             `json:"tlsCACert"`
+	TLSClientCert         string             `json:"tlsClientCert"`
+	TLSClientKey          string             `json:"tlsClientKey"`
 	RequestedHeartbeat    time.Duration      `json:"requestedHeartbeat"`
 	RequestedMaxFrameSize int                `json:"requestedMaxFrameSize"`
 	WriteBuffer           int                `json:"writeBuffer"`
@@ -34,21 +66,45 @@ type RabbitMQStreamOptions struct {
 	NoDelay               bool               `json:"noDelay"`
 	StreamOptions         *StreamOptions     `json:"streamOptions"`
 	ExchangesOptions      []*ExchangeOptions `json:"exchangesOptions"`
+	QueuesOptions         []*QueueOptions    `json:"queuesOptions"`
 	BindingsOptions       []*BindingOptions  `json:"bindingsOptions"`
+	ReconnectOptions      *ReconnectOptions  `json:"reconnectOptions"`
 }
 
 type RabbitMQStreamClient struct {
 	RabbitMQOptions *RabbitMQStreamOptions
-	Env             *stream.Environment
-	Stream          Stream
-	Exchanges       []Exchange
-	Bindings        []Binding
+	// env is replaced by the reconnections while the consumers and the readers use it
+	env                atomic.Pointer[stream.Environment]
+	endpointIndex      atomic.Int64
+	nodeEnvsMutex      sync.Mutex
+	nodeEnvs           map[string]*stream.Environment
+	queueMutex         sync.Mutex
+	queueSubscriptions map[*QueueSubscription]struct{}
+	Stream             Stream
+	Exchanges          []Exchange
+	Queues             []Queue
+	Bindings           []Binding
+	Consumers          *ConsumerRegistry
+	// connectMutex serializes the connections, so concurrent callers don't create several environments
+	connectMutex      sync.Mutex
+	reconnectMutex    sync.Mutex
+	reconnection      *reconnection
+	stateMutex        sync.Mutex
+	connectionState   ConnectionState
+	disposed          chan struct{}
+	disposeOnce       sync.Once
+	tokenMutex        sync.Mutex
+	tokenSource       oauth2.TokenSource
+	tokenRefreshTimer *time.Timer
+	// isReconciled is set once the topology was reconciled, so the reconnections only log the reconcile errors
+	isReconciled atomic.Bool
 }
 
-const timeToReconnect time.Duration = 2000 * time.Millisecond
-
 func NewRabbitMQStreamClient() *RabbitMQStreamClient {
-	return &RabbitMQStreamClient{}
+	return &RabbitMQStreamClient{
+		Consumers: NewConsumerRegistry(),
+		disposed:  make(chan struct{}),
+	}
 }
 
 func NewRabbitMQStreamOptions() *RabbitMQStreamOptions {
@@ -61,46 +117,79 @@ func (client *RabbitMQStreamClient) SetRabbitMQOptions(rabbitmqStreamOptions *Ra
 }
 
 func (client *RabbitMQStreamClient) SetEnv() (*RabbitMQStreamClient, error) {
+	newEnvOptions, err := client.getEnvOptionsFactory()
+	if err != nil {
+		return client, err
+	}
+
 	// Connect to the broker
-	env, err := stream.NewEnvironment(
-		stream.NewEnvironmentOptions().
-			SetHost(client.RabbitMQOptions.Host).
-			SetPort(client.RabbitMQOptions.StreamPort).
-			SetVHost(client.RabbitMQOptions.VHost).
-			SetUser(client.RabbitMQOptions.User).
-			SetPassword(client.RabbitMQOptions.Password).
-			IsTLS(client.RabbitMQOptions.IsTLS).
-			//SetTLSConfig(&tls.Config{}). - TLSConfig is not supported at the moment
-			SetRequestedHeartbeat(client.RabbitMQOptions.RequestedHeartbeat * time.Second).
-			SetRequestedMaxFrameSize(client.RabbitMQOptions.RequestedMaxFrameSize).
-			SetWriteBuffer(client.RabbitMQOptions.WriteBuffer).
-			SetReadBuffer(client.RabbitMQOptions.ReadBuffer).
-			SetNoDelay(client.RabbitMQOptions.NoDelay).
-			SetAddressResolver(stream.AddressResolver{
-				Host: client.RabbitMQOptions.Host,
-				Port: client.RabbitMQOptions.StreamPort,
-			},
-			),
-	)
+	env, err := client.newEnvironment(newEnvOptions)
 
-	client.Env = env
+	client.env.Store(env)
 
 	return client, err
 }
 
+// getEnvOptionsFactory returns a factory of the environment options without the brokers,
+// so every environment that is created for an endpoint or a node shares the same settings.
+func (client *RabbitMQStreamClient) getEnvOptionsFactory() (func() *stream.EnvironmentOptions, error) {
+	user, password, err := client.getCredentials()
+	if err != nil {
+		return nil, err
+	}
+	options := client.RabbitMQOptions
+	var tlsConfig *tls.Config
+	if options.IsTLS {
+		if tlsConfig, err = options.getTLSConfig(); err != nil {
+			return nil, err
+		}
+	}
+	return func() *stream.EnvironmentOptions {
+		envOptions := stream.NewEnvironmentOptions().
+			SetVHost(options.VHost).
+			SetUser(user).
+			SetPassword(password).
+			SetSaslConfiguration(options.getSaslMechanism()).
+			SetRequestedHeartbeat(options.RequestedHeartbeat * time.Second).
+			SetRequestedMaxFrameSize(options.RequestedMaxFrameSize).
+			SetWriteBuffer(options.WriteBuffer).
+			SetReadBuffer(options.ReadBuffer).
+			SetNoDelay(options.NoDelay)
+		if tlsConfig != nil {
+			envOptions.SetTLSConfig(tlsConfig)
+		}
+		return envOptions
+	}, nil
+}
+
+// SetStream leaves the stream empty when the datasource has no stream settings, its queries name their own streams.
 func (client *RabbitMQStreamClient) SetStream() *RabbitMQStreamClient {
-	client.Stream = client.RabbitMQOptions.StreamOptions
+	client.Stream = nil
+	if client.RabbitMQOptions.StreamOptions != nil {
+		client.Stream = client.RabbitMQOptions.StreamOptions
+	}
 	return client
 }
 
+// SetExchanges (like SetQueues and SetBindings) is called again by every reconnection, so it starts over.
 func (client *RabbitMQStreamClient) SetExchanges() *RabbitMQStreamClient {
+	client.Exchanges = nil
 	for exchangeIndex := 0; exchangeIndex < len(client.RabbitMQOptions.ExchangesOptions); exchangeIndex += 1 {
 		client.Exchanges = append(client.Exchanges, client.RabbitMQOptions.ExchangesOptions[exchangeIndex])
 	}
 	return client
 }
 
+func (client *RabbitMQStreamClient) SetQueues() *RabbitMQStreamClient {
+	client.Queues = nil
+	for queueIndex := 0; queueIndex < len(client.RabbitMQOptions.QueuesOptions); queueIndex += 1 {
+		client.Queues = append(client.Queues, client.RabbitMQOptions.QueuesOptions[queueIndex])
+	}
+	return client
+}
+
 func (client *RabbitMQStreamClient) SetBindings() *RabbitMQStreamClient {
+	client.Bindings = nil
 	for bindingIndex := 0; bindingIndex < len(client.RabbitMQOptions.BindingsOptions); bindingIndex += 1 {
 		client.Bindings = append(client.Bindings, client.RabbitMQOptions.BindingsOptions[bindingIndex])
 	}
@@ -108,10 +197,17 @@ func (client *RabbitMQStreamClient) SetBindings() *RabbitMQStreamClient {
 }
 
 func (client *RabbitMQStreamClient) CreateStream() (*RabbitMQStreamClient, error) {
-	return client, client.Stream.CreateStream(client.Env)
+	if client.Stream == nil {
+		return client, nil
+	}
+	env, err := client.getEnv()
+	if err != nil {
+		return client, err
+	}
+	return client, client.Stream.CreateStream(env)
 }
 
-func (client *RabbitMQStreamClient) CreateExchanges(ch *amqp.Channel) (*RabbitMQStreamClient, error) {
+func (client *RabbitMQStreamClient) CreateExchanges(ch TopologyChannel) (*RabbitMQStreamClient, error) {
 	for exchangeIndex := 0; exchangeIndex < len(client.Exchanges); exchangeIndex += 1 {
 		if err := client.Exchanges[exchangeIndex].CreateExchange(ch); err != nil {
 			return client, err
@@ -120,7 +216,7 @@ func (client *RabbitMQStreamClient) CreateExchanges(ch *amqp.Channel) (*RabbitMQ
 	return client, nil
 }
 
-func (client *RabbitMQStreamClient) DisposeExchanges(ch *amqp.Channel) (*RabbitMQStreamClient, error) {
+func (client *RabbitMQStreamClient) DisposeExchanges(ch TopologyChannel) (*RabbitMQStreamClient, error) {
 	for exchangeIndex := 0; exchangeIndex < len(client.Exchanges); exchangeIndex += 1 {
 		if err := client.Exchanges[exchangeIndex].DisposeExchange(ch); err != nil {
 			return client, err
@@ -129,7 +225,25 @@ func (client *RabbitMQStreamClient) DisposeExchanges(ch *amqp.Channel) (*RabbitM
 	return client, nil
 }
 
-func (client *RabbitMQStreamClient) CreateBindings(ch *amqp.Channel) (*RabbitMQStreamClient, error) {
+func (client *RabbitMQStreamClient) CreateQueues(ch TopologyChannel) (*RabbitMQStreamClient, error) {
+	for queueIndex := 0; queueIndex < len(client.Queues); queueIndex += 1 {
+		if err := client.Queues[queueIndex].CreateQueue(ch); err != nil {
+			return client, err
+		}
+	}
+	return client, nil
+}
+
+func (client *RabbitMQStreamClient) DisposeQueues(ch TopologyChannel) (*RabbitMQStreamClient, error) {
+	for queueIndex := 0; queueIndex < len(client.Queues); queueIndex += 1 {
+		if err := client.Queues[queueIndex].DisposeQueue(ch); err != nil {
+			return client, err
+		}
+	}
+	return client, nil
+}
+
+func (client *RabbitMQStreamClient) CreateBindings(ch TopologyChannel) (*RabbitMQStreamClient, error) {
 	for bindingIndex := 0; bindingIndex < len(client.Bindings); bindingIndex += 1 {
 		if err := client.Bindings[bindingIndex].CreateBinding(ch); err != nil {
 			return client, err
@@ -138,7 +252,7 @@ func (client *RabbitMQStreamClient) CreateBindings(ch *amqp.Channel) (*RabbitMQS
 	return client, nil
 }
 
-func (client *RabbitMQStreamClient) DisposeBindings(ch *amqp.Channel) (*RabbitMQStreamClient, error) {
+func (client *RabbitMQStreamClient) DisposeBindings(ch TopologyChannel) (*RabbitMQStreamClient, error) {
 	for bindingIndex := 0; bindingIndex < len(client.Bindings); bindingIndex += 1 {
 		if err := client.Bindings[bindingIndex].DisposeBinding(ch); err != nil {
 			return client, err
@@ -148,68 +262,123 @@ func (client *RabbitMQStreamClient) DisposeBindings(ch *amqp.Channel) (*RabbitMQ
 }
 
 func (client *RabbitMQStreamClient) IsConnected() bool {
-	return !client.Env.IsClosed()
+	env := client.env.Load()
+	return env != nil && !env.IsClosed()
+}
+
+// getEnv returns the environment of the datasource, which is replaced by every reconnection.
+func (client *RabbitMQStreamClient) getEnv() (*stream.Environment, error) {
+	env := client.env.Load()
+	if env == nil {
+		return nil, ErrNotConnected
+	}
+	return env, nil
 }
 
 func (client *RabbitMQStreamClient) createAmqpConnection() (*amqp.Connection, error) {
 	options := client.RabbitMQOptions
-	conn, err := amqp.Dial(fmt.Sprintf("amqp://%s:%s@%s:%d/%s", options.User, options.Password, options.Host, options.AmqpPort, options.VHost))
-	return conn, err
-
+	user, password, err := client.getCredentials()
+	if err != nil {
+		return nil, err
+	}
+	// the credentials and the vhost are passed by the config, so they are never interpolated into the URL
+	config := amqp.Config{
+		SASL:      client.getAmqpAuthentication(user, password),
+		Vhost:     options.VHost,
+		Heartbeat: amqpHeartbeat,
+		Locale:    amqpLocale,
+	}
+	scheme := "amqp"
+	if options.IsTLS {
+		scheme = "amqps"
+		if config.TLSClientConfig, err = options.getTLSConfig(); err != nil {
+			return nil, err
+		}
+	}
+	endpoints, err := client.getRotatedEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	for _, endpoint := range endpoints {
+		amqpURL := url.URL{Scheme: scheme, Host: net.JoinHostPort(endpoint.host, strconv.Itoa(options.AmqpPort))}
+		var conn *amqp.Connection
+		if conn, err = amqp.DialConfig(amqpURL.String(), config); err == nil {
+			client.setConnectedEndpoint(endpoint)
+			return conn, nil
+		}
+		log.DefaultLogger.Warn("Failed to connect to the RabbitMQ endpoint with AMQP, trying the next one", "host", endpoint.host, "port", options.AmqpPort, "error", err)
+	}
+	return nil, err
 }
 
+// Connect creates the environment and the RabbitMQ objects, it does nothing while the client is connected
+// with a reconciled topology.
 func (client *RabbitMQStreamClient) Connect() (Client, error) {
+	client.connectMutex.Lock()
+	defer client.connectMutex.Unlock()
+	if client.IsConnected() && client.isReconciled.Load() {
+		return client, nil
+	}
+	return client, client.connect()
+}
+
+// connect must be called while holding the connect mutex of the client.
+func (client *RabbitMQStreamClient) connect() error {
 	log.DefaultLogger.Debug("Trying to set the RabbitMQ environment...")
 	_, err := client.SetEnv()
 	if err != nil {
 		log.DefaultLogger.Error("Couldn't set the RabbitMQ environment", "error", err)
-		return client, err
+		return err
 	}
 	log.DefaultLogger.Debug("Successfully set the RabbitMQ environment!")
 
 	log.DefaultLogger.Debug("Trying to set the RabbitMQ objects...")
 	client.SetStream()
 	client.SetExchanges()
+	client.SetQueues()
 	client.SetBindings()
 	log.DefaultLogger.Debug("Successfully set the RabbitMQ objects!")
 
-	log.DefaultLogger.Debug("Create new channel to the RabbitMQ...")
-	conn, err := client.createAmqpConnection()
-	if err != nil {
-		log.DefaultLogger.Error("Couldn't connect to the RabbitMQ with AMQP connection", "error", err)
-		return client, err
-	}
-	defer conn.Close()
-	ch, err := conn.Channel()
-	if err != nil {
-		return client, err
-	}
-	defer ch.Close()
-	log.DefaultLogger.Debug("Successfully created new channel to the RabbitMQ...")
-
-	log.DefaultLogger.Debug("Trying to create the RabbitMQ objects...")
-	_, err = client.CreateExchanges(ch)
-	if err != nil {
-		return client, err
-	}
-	_, err = client.CreateStream()
-	if err != nil {
-		return client, err
+	log.DefaultLogger.Debug("Trying to reconcile the RabbitMQ topology...")
+	diff, err := client.ReconcileTopology(false)
+	if err != nil && !client.isReconciled.Load() {
+		log.DefaultLogger.Error("Couldn't reconcile the RabbitMQ topology", "error", err)
+		// the next Connect creates its own environment
+		if closeErr := client.closeEnvironment(); closeErr != nil {
+			log.DefaultLogger.Debug("Failed to close the environment of the failed connection", "error", closeErr)
+		}
+		return err
 	}
-	_, err = client.CreateBindings(ch)
 	if err != nil {
-		return client, err
+		// the topology was already reconciled by the first connection, so the reconnection goes on with it
+		log.DefaultLogger.Error("Couldn't reconcile the RabbitMQ topology of the reconnection", "error", err)
+	} else {
+		client.isReconciled.Store(true)
+		log.DefaultLogger.Debug("Successfully reconciled the RabbitMQ topology!", "changes", diff.String())
 	}
-	log.DefaultLogger.Debug("Successfully created the RabbitMQ objects!")
 
-	return client, nil
+	client.setConnectionState(CONNECTION_STATUS_CONNECTED, 0, nil)
+	return nil
 }
 
 func (client *RabbitMQStreamClient) CloseConnection() error {
-	if err := client.Stream.DisposeStream(client.Env); err != nil {
+	env, err := client.getEnv()
+	if err != nil {
+		return err
+	}
+	if err := client.Consumers.CloseAll(); err != nil {
 		return err
 	} else {
-		log.DefaultLogger.Debug("Disposed Stream", "RabbitMQ Stream", client.ToString())
+		log.DefaultLogger.Debug("Closed consumers", "RabbitMQ Stream", client.ToString())
+	}
+	client.closeNodeEnvironments()
+
+	if client.Stream != nil {
+		if err := client.Stream.DisposeStream(env); err != nil {
+			return err
+		} else {
+			log.DefaultLogger.Debug("Disposed Stream", "RabbitMQ Stream", client.ToString())
+		}
 	}
 
 	log.DefaultLogger.Debug("Create new channel to the RabbitMQ...")
@@ -231,13 +400,19 @@ func (client *RabbitMQStreamClient) CloseConnection() error {
 		log.DefaultLogger.Debug("Disposed bindings", "RabbitMQ Stream", client.ToString())
 	}
 
+	if _, err := client.DisposeQueues(ch); err != nil {
+		return err
+	} else {
+		log.DefaultLogger.Debug("Disposed queues", "RabbitMQ Stream", client.ToString())
+	}
+
 	if _, err := client.DisposeExchanges(ch); err != nil {
 		return err
 	} else {
 		log.DefaultLogger.Debug("Disposed exchanges", "RabbitMQ Stream", client.ToString())
 	}
 
-	if err := client.Env.Close(); err != nil {
+	if err := env.Close(); err != nil {
 		return err
 	} else {
 		log.DefaultLogger.Debug("Closed RabbitMQ environment", "RabbitMQ Stream", client.ToString())
@@ -246,29 +421,131 @@ func (client *RabbitMQStreamClient) CloseConnection() error {
 	return nil
 }
 
-func (client *RabbitMQStreamClient) Reconnect() Client {
-	for {
-		time.Sleep(timeToReconnect)
-		log.DefaultLogger.Debug("Trying to reconnect to RabbitMQ", "RabbitMQ Stream", client.ToString())
+// closeEnvironment closes the consumers and the environment of a lost connection, without
+// disposing the RabbitMQ objects, so they are only declared again by the next Connect.
+func (client *RabbitMQStreamClient) closeEnvironment() error {
+	if err := client.Consumers.CloseAll(); err != nil {
+		log.DefaultLogger.Debug("Failed to close the consumers of the lost connection", "error", err)
+	}
+	client.closeNodeEnvironments()
+	env := client.env.Load()
+	if env == nil || env.IsClosed() {
+		return nil
+	}
+	return env.Close()
+}
 
-		err := client.CloseConnection()
-		if err != nil {
-			continue
-		}
-		_, err = client.Connect()
-		if err != nil {
-			continue
+// Reconnect recreates the connection and the RabbitMQ objects. Every consumer of a lost
+// connection calls it, so all of them wait for the same reconnection, and it does nothing once
+// the connection is healthy again. It returns when the reconnection is over or the context is done.
+func (client *RabbitMQStreamClient) Reconnect(ctx context.Context) error {
+	client.reconnectMutex.Lock()
+	if client.isDisposed() {
+		client.reconnectMutex.Unlock()
+		log.DefaultLogger.Debug("RabbitMQ client was disposed, no need to reconnect", "RabbitMQ Stream", client.ToString())
+		return ErrClientDisposed
+	}
+	current := client.reconnection
+	if current == nil {
+		if client.isHealthy() {
+			client.reconnectMutex.Unlock()
+			log.DefaultLogger.Debug("RabbitMQ connection is healthy, no need to reconnect", "RabbitMQ Stream", client.ToString())
+			return nil
 		}
-		break
+		current = &reconnection{done: make(chan struct{})}
+		client.reconnection = current
+		go client.reconnect(current)
 	}
-	return client
+	client.reconnectMutex.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-current.done:
+		return current.err
+	}
+}
+
+func (client *RabbitMQStreamClient) isDisposed() bool {
+	select {
+	case <-client.disposed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (client *RabbitMQStreamClient) GetStreamOptions() *StreamOptions {
+	return client.RabbitMQOptions.StreamOptions
+}
+
+// isHealthy checks that the environment is open and the stream of the datasource still exists.
+func (client *RabbitMQStreamClient) isHealthy() bool {
+	env := client.env.Load()
+	if env == nil || env.IsClosed() {
+		return false
+	}
+	if client.RabbitMQOptions.StreamOptions == nil {
+		return true
+	}
+	exists, err := client.RabbitMQOptions.StreamOptions.exists(env)
+	return err == nil && exists
+}
+
+func (client *RabbitMQStreamClient) Consume(streamOptions *StreamOptions, messageHandler stream.MessagesHandler) (*ConsumerSubscription, error) {
+	if streamOptions.PreferReplica {
+		return client.subscribeToReplica(streamOptions, messageHandler)
+	}
+	env, err := client.getEnv()
+	if err != nil {
+		return nil, err
+	}
+	return client.Consumers.Subscribe(env, streamOptions, messageHandler)
+}
+
+func (client *RabbitMQStreamClient) Read(ctx context.Context, streamOptions *StreamOptions, offset stream.OffsetSpecification, readHandler ReadHandler) error {
+	env, err := client.getEnv()
+	if err != nil {
+		return err
+	}
+	return streamOptions.Read(ctx, env, offset, readHandler)
+}
+
+func (client *RabbitMQStreamClient) OffsetAt(ctx context.Context, streamOptions *StreamOptions, timestamp time.Time) (int64, error) {
+	env, err := client.getEnv()
+	if err != nil {
+		return -1, err
+	}
+	return streamOptions.OffsetAt(ctx, env, timestamp)
 }
 
-func (client *RabbitMQStreamClient) Consume(messageHandler stream.MessagesHandler) (*stream.Consumer, error) {
-	return client.Stream.Consume(client.Env, messageHandler)
+func (client *RabbitMQStreamClient) FirstOffset(streamOptions *StreamOptions) (int64, error) {
+	env, err := client.getEnv()
+	if err != nil {
+		return 0, err
+	}
+	return streamOptions.FirstOffset(env)
+}
+
+func (client *RabbitMQStreamClient) Partitions(streamOptions *StreamOptions) ([]string, error) {
+	env, err := client.getEnv()
+	if err != nil {
+		return nil, err
+	}
+	return streamOptions.Partitions(env)
 }
 
 func (client *RabbitMQStreamClient) Dispose() {
+	client.disposeOnce.Do(func() {
+		close(client.disposed)
+	})
+	client.tokenMutex.Lock()
+	if client.tokenRefreshTimer != nil {
+		client.tokenRefreshTimer.Stop()
+	}
+	client.tokenMutex.Unlock()
+	client.setConnectionState(CONNECTION_STATUS_DISPOSED, 0, nil)
+	client.closeQueueSubscriptions()
 	if client.IsConnected() {
 		log.DefaultLogger.Debug("Disposing RabbitMQ Stream", "RabbitMQ Stream", client.ToString())
 		err := client.CloseConnection()
@@ -279,10 +556,14 @@ func (client *RabbitMQStreamClient) Dispose() {
 }
 
 func (client *RabbitMQStreamClient) ToString() string {
+	streamName := ""
+	if client.RabbitMQOptions.StreamOptions != nil {
+		streamName = client.RabbitMQOptions.StreamOptions.StreamName
+	}
 	return fmt.Sprintf(
 		"{ Host: %s, VHost: %s, StreamName: %v }",
 		client.RabbitMQOptions.Host,
 		client.RabbitMQOptions.VHost,
-		client.RabbitMQOptions.StreamOptions.StreamName,
+		streamName,
 	)
 }
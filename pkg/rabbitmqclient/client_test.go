@@ -0,0 +1,82 @@
+package rabbitmqclient
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+// closedPort returns a local port that refuses the connections.
+func closedPort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	return port
+}
+
+// TestReconnectConcurrently runs the reconnection of an unreachable broker while the environment is read,
+// it is meant to be run with the race detector (go test -race).
+func TestReconnectConcurrently(t *testing.T) {
+	port := closedPort(t)
+	client := NewRabbitMQStreamClient().SetRabbitMQOptions(&RabbitMQStreamOptions{
+		Host:          "127.0.0.1",
+		StreamPort:    port,
+		AmqpPort:      port,
+		StreamOptions: &StreamOptions{StreamName: "rabbitmq.stream"},
+		ReconnectOptions: &ReconnectOptions{
+			InitialIntervalMs: 1,
+			MaxIntervalMs:     1,
+			MaxAttempts:       3,
+		},
+	})
+	defer client.Dispose()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var group sync.WaitGroup
+	for reconnects := 0; reconnects < 3; reconnects += 1 {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			if err := client.Reconnect(ctx); err == nil {
+				t.Errorf("the reconnection to a closed port succeeded")
+			}
+		}()
+	}
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			client.IsConnected()
+			if subscription, err := client.Consume(&StreamOptions{StreamName: "rabbitmq.stream"}, func(stream.ConsumerContext, *amqp.Message) {}); err == nil {
+				subscription.Close()
+				t.Errorf("the consumer of a disconnected client was created")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	group.Wait()
+	close(stop)
+	readers.Wait()
+
+	if state := client.GetConnectionState(); state.Status != CONNECTION_STATUS_FAILED {
+		t.Errorf("unexpected connection state: %s", state.Status)
+	}
+}
@@ -0,0 +1,322 @@
+package rabbitmqclient
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+// ConsumerKey identifies the consumers that can be shared by several subscriptions,
+// since they read the same messages from the same position in the stream.
+type ConsumerKey struct {
+	StreamName   string
+	ConsumerName string
+	Offset       string
+	Filter       string
+}
+
+// ConsumerRegistry runs many stream consumers on one environment. Every consumer
+// is reference counted by its subscriptions and closed once the last one is closed.
+type ConsumerRegistry struct {
+	mutex         sync.Mutex
+	consumers     map[ConsumerKey]*registeredConsumer
+	nextHandlerId int
+	// consume creates the consumers of the registry, the tests create fake consumers without a broker
+	consume consumeFunc
+}
+
+// streamConsumer is the part of a stream consumer that is used by the registry.
+type streamConsumer interface {
+	GetName() string
+	StoreOffset() error
+	NotifyClose() stream.ChannelClose
+	Close() error
+}
+
+type consumeFunc func(env *stream.Environment, streamOptions *StreamOptions, messagesHandler stream.MessagesHandler, consumerUpdateHandler ConsumerUpdateHandler) (streamConsumer, error)
+
+type registeredConsumer struct {
+	consumer streamConsumer
+	// storeOffset stores the offset of the consumer periodically and once it is closed
+	storeOffset bool
+	// isStandby is set while a single active consumer is not the active one, so it must not store its offset
+	isStandby     atomic.Bool
+	handlersMutex sync.RWMutex
+	handlers      map[int]stream.MessagesHandler
+	closed        chan struct{}
+	closeOnce     sync.Once
+	// ready is closed once the consumer was created, err is set when it failed
+	ready chan struct{}
+	err   error
+}
+
+// ConsumerSubscription is a single reference to a registered consumer.
+type ConsumerSubscription struct {
+	registry  *ConsumerRegistry
+	key       ConsumerKey
+	handlerId int
+	entry     *registeredConsumer
+	closeOnce sync.Once
+}
+
+func NewConsumerRegistry() *ConsumerRegistry {
+	return &ConsumerRegistry{
+		consumers: make(map[ConsumerKey]*registeredConsumer),
+		consume:   consumeStream,
+	}
+}
+
+func consumeStream(env *stream.Environment, streamOptions *StreamOptions, messagesHandler stream.MessagesHandler, consumerUpdateHandler ConsumerUpdateHandler) (streamConsumer, error) {
+	consumer, err := streamOptions.Consume(env, messagesHandler, consumerUpdateHandler)
+	if err != nil {
+		return nil, err
+	}
+	return consumer, nil
+}
+
+// Subscribe adds the messages handler to the consumer of the stream options, and
+// creates the consumer on the environment if no subscription is using it yet. The consumer
+// is created outside of the lock, so a slow stream doesn't block the other subscriptions,
+// and the subscriptions of the same consumer wait until it is created.
+func (registry *ConsumerRegistry) Subscribe(env *stream.Environment, streamOptions *StreamOptions, messagesHandler stream.MessagesHandler) (*ConsumerSubscription, error) {
+	key := streamOptions.consumerKey()
+	for {
+		registry.mutex.Lock()
+		entry, exists := registry.consumers[key]
+		if exists && !entry.isClosed() {
+			if !entry.isReady() {
+				registry.mutex.Unlock()
+				<-entry.ready
+				if entry.err != nil {
+					return nil, entry.err
+				}
+				continue
+			}
+			subscription := registry.addHandler(key, entry, messagesHandler)
+			registry.mutex.Unlock()
+			return subscription, nil
+		}
+
+		entry = &registeredConsumer{
+			handlers: make(map[int]stream.MessagesHandler),
+			closed:   make(chan struct{}),
+			ready:    make(chan struct{}),
+		}
+		// a single active consumer is a standby until it is promoted
+		entry.isStandby.Store(streamOptions.SingleActiveConsumer)
+		registry.consumers[key] = entry
+		// the handler is added before the consumer is created, so the first messages are not lost
+		subscription := registry.addHandler(key, entry, messagesHandler)
+		registry.mutex.Unlock()
+
+		if err := registry.createConsumer(env, streamOptions, key, entry); err != nil {
+			return nil, err
+		}
+		return subscription, nil
+	}
+}
+
+// createConsumer creates the consumer of a pending entry, and forgets the entry when it failed or
+// when the registry was closed in the meantime.
+func (registry *ConsumerRegistry) createConsumer(env *stream.Environment, streamOptions *StreamOptions, key ConsumerKey, entry *registeredConsumer) error {
+	defer close(entry.ready)
+
+	consumer, err := registry.consume(env, streamOptions, entry.dispatch, entry.onConsumerUpdate)
+	registry.mutex.Lock()
+	if err == nil && entry.isClosed() {
+		err = fmt.Errorf("the consumer %s was closed while it was created", key.ConsumerName)
+		_ = consumer.Close()
+	}
+	if err != nil {
+		entry.err = err
+		if registry.consumers[key] == entry {
+			delete(registry.consumers, key)
+		}
+		registry.mutex.Unlock()
+		entry.markClosed()
+		return err
+	}
+	entry.consumer = consumer
+	registry.mutex.Unlock()
+
+	go registry.watch(key, entry, consumer.NotifyClose())
+	if streamOptions.OffsetStorage.IsPeriodic() {
+		entry.storeOffset = true
+		go entry.storeOffsetPeriodically(streamOptions.OffsetStorage.GetInterval())
+	}
+	log.DefaultLogger.Debug("Registered new consumer", "consumer", fmt.Sprintf("%+v", key))
+	return nil
+}
+
+// addHandler must be called while holding the mutex of the registry.
+func (registry *ConsumerRegistry) addHandler(key ConsumerKey, entry *registeredConsumer, messagesHandler stream.MessagesHandler) *ConsumerSubscription {
+	registry.nextHandlerId += 1
+	entry.handlersMutex.Lock()
+	entry.handlers[registry.nextHandlerId] = messagesHandler
+	entry.handlersMutex.Unlock()
+
+	return &ConsumerSubscription{
+		registry:  registry,
+		key:       key,
+		handlerId: registry.nextHandlerId,
+		entry:     entry,
+	}
+}
+
+// CloseAll closes every registered consumer, for example before the environment is closed.
+// The consumers that are still being created are closed once they are created.
+func (registry *ConsumerRegistry) CloseAll() error {
+	registry.mutex.Lock()
+	entries := registry.consumers
+	registry.consumers = make(map[ConsumerKey]*registeredConsumer)
+	for key, entry := range entries {
+		if !entry.isReady() {
+			entry.markClosed()
+			delete(entries, key)
+		}
+	}
+	registry.mutex.Unlock()
+
+	var lastErr error
+	for key, entry := range entries {
+		if err := entry.close(); err != nil {
+			lastErr = failOnError(err, fmt.Sprintf("Failed to close the consumer: %s", key.ConsumerName))
+		}
+	}
+	return lastErr
+}
+
+func (registry *ConsumerRegistry) Count() int {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	return len(registry.consumers)
+}
+
+// watch forgets the consumer once it was closed by the broker, so the next
+// subscription creates a new one instead of joining a dead consumer.
+func (registry *ConsumerRegistry) watch(key ConsumerKey, entry *registeredConsumer, notifyClose stream.ChannelClose) {
+	select {
+	case event := <-notifyClose:
+		log.DefaultLogger.Debug("Consumer was closed", "consumer", fmt.Sprintf("%+v", key), "reason", event.Reason, "error", event.Err)
+	case <-entry.closed:
+	}
+	registry.forget(key, entry)
+	entry.markClosed()
+}
+
+func (registry *ConsumerRegistry) forget(key ConsumerKey, entry *registeredConsumer) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if registry.consumers[key] == entry {
+		delete(registry.consumers, key)
+	}
+}
+
+func (registry *ConsumerRegistry) unsubscribe(subscription *ConsumerSubscription) error {
+	registry.mutex.Lock()
+	entry := subscription.entry
+	entry.handlersMutex.Lock()
+	delete(entry.handlers, subscription.handlerId)
+	isLastSubscription := len(entry.handlers) == 0
+	entry.handlersMutex.Unlock()
+	if isLastSubscription && registry.consumers[subscription.key] == entry {
+		delete(registry.consumers, subscription.key)
+	}
+	registry.mutex.Unlock()
+
+	if !isLastSubscription {
+		return nil
+	}
+	log.DefaultLogger.Debug("Closing consumer without subscriptions", "consumer", fmt.Sprintf("%+v", subscription.key))
+	return entry.close()
+}
+
+func (entry *registeredConsumer) dispatch(consumerContext stream.ConsumerContext, message *amqp.Message) {
+	entry.handlersMutex.RLock()
+	defer entry.handlersMutex.RUnlock()
+	for _, messagesHandler := range entry.handlers {
+		messagesHandler(consumerContext, message)
+	}
+}
+
+func (entry *registeredConsumer) onConsumerUpdate(isActive bool) {
+	entry.isStandby.Store(!isActive)
+}
+
+func (entry *registeredConsumer) storeOffsetPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-entry.closed:
+			return
+		case <-ticker.C:
+			if entry.isStandby.Load() {
+				continue
+			}
+			if err := entry.consumer.StoreOffset(); err != nil {
+				log.DefaultLogger.Warn("Failed to store the offset of the consumer", "consumer", entry.consumer.GetName(), "error", err)
+			}
+		}
+	}
+}
+
+func (entry *registeredConsumer) isReady() bool {
+	select {
+	case <-entry.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func (entry *registeredConsumer) isClosed() bool {
+	select {
+	case <-entry.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (entry *registeredConsumer) close() error {
+	if entry.isClosed() {
+		return nil
+	}
+	if entry.storeOffset && !entry.isStandby.Load() {
+		if err := entry.consumer.StoreOffset(); err != nil {
+			log.DefaultLogger.Warn("Failed to store the offset of the closed consumer", "consumer", entry.consumer.GetName(), "error", err)
+		}
+	}
+	err := entry.consumer.Close()
+	entry.markClosed()
+	if err == stream.AlreadyClosed {
+		return nil
+	}
+	return err
+}
+
+func (entry *registeredConsumer) markClosed() {
+	entry.closeOnce.Do(func() {
+		close(entry.closed)
+	})
+}
+
+// NotifyClose is closed once the shared consumer is closed, by the broker or by the registry.
+func (subscription *ConsumerSubscription) NotifyClose() <-chan struct{} {
+	return subscription.entry.closed
+}
+
+// Close releases the subscription, the consumer is closed with its last subscription.
+func (subscription *ConsumerSubscription) Close() error {
+	var err error
+	subscription.closeOnce.Do(func() {
+		err = subscription.registry.unsubscribe(subscription)
+	})
+	return err
+}
@@ -0,0 +1,267 @@
+package rabbitmqclient
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+// fakeConsumer is a stream consumer without a broker, it is closed by the broker with closeByBroker.
+type fakeConsumer struct {
+	messagesHandler stream.MessagesHandler
+	notifyClose     chan stream.Event
+	mutex           sync.Mutex
+	closes          int
+}
+
+func (consumer *fakeConsumer) GetName() string {
+	return "fake"
+}
+
+func (consumer *fakeConsumer) StoreOffset() error {
+	return nil
+}
+
+func (consumer *fakeConsumer) NotifyClose() stream.ChannelClose {
+	return consumer.notifyClose
+}
+
+func (consumer *fakeConsumer) Close() error {
+	consumer.mutex.Lock()
+	defer consumer.mutex.Unlock()
+	consumer.closes += 1
+	return nil
+}
+
+func (consumer *fakeConsumer) getCloses() int {
+	consumer.mutex.Lock()
+	defer consumer.mutex.Unlock()
+	return consumer.closes
+}
+
+func (consumer *fakeConsumer) closeByBroker() {
+	consumer.notifyClose <- stream.Event{Reason: "deleted"}
+}
+
+// fakeConsumers records the consumers that were created by a registry. The creation waits
+// until release is closed when it is set, and fails with err when it is set.
+type fakeConsumers struct {
+	mutex     sync.Mutex
+	consumers []*fakeConsumer
+	release   chan struct{}
+	err       error
+}
+
+func (fakes *fakeConsumers) consume(_ *stream.Environment, _ *StreamOptions, messagesHandler stream.MessagesHandler, _ ConsumerUpdateHandler) (streamConsumer, error) {
+	if fakes.release != nil {
+		<-fakes.release
+	}
+	fakes.mutex.Lock()
+	defer fakes.mutex.Unlock()
+	if fakes.err != nil {
+		return nil, fakes.err
+	}
+	consumer := &fakeConsumer{
+		messagesHandler: messagesHandler,
+		notifyClose:     make(chan stream.Event, 1),
+	}
+	fakes.consumers = append(fakes.consumers, consumer)
+	return consumer, nil
+}
+
+func (fakes *fakeConsumers) created() []*fakeConsumer {
+	fakes.mutex.Lock()
+	defer fakes.mutex.Unlock()
+	return append([]*fakeConsumer{}, fakes.consumers...)
+}
+
+func newFakeRegistry() (*ConsumerRegistry, *fakeConsumers) {
+	fakes := &fakeConsumers{}
+	registry := NewConsumerRegistry()
+	registry.consume = fakes.consume
+	return registry, fakes
+}
+
+func subscribe(t *testing.T, registry *ConsumerRegistry, streamName string, messagesHandler stream.MessagesHandler) *ConsumerSubscription {
+	t.Helper()
+	subscription, err := registry.Subscribe(nil, &StreamOptions{StreamName: streamName}, messagesHandler)
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %v", err)
+	}
+	return subscription
+}
+
+func TestConsumerRegistrySubscriptions(t *testing.T) {
+	registry, fakes := newFakeRegistry()
+	var received []string
+	handler := func(name string) stream.MessagesHandler {
+		return func(stream.ConsumerContext, *amqp.Message) {
+			received = append(received, name)
+		}
+	}
+
+	first := subscribe(t, registry, "rabbitmq.stream", handler("first"))
+	second := subscribe(t, registry, "rabbitmq.stream", handler("second"))
+	other := subscribe(t, registry, "rabbitmq.other", handler("other"))
+
+	consumers := fakes.created()
+	if len(consumers) != 2 || registry.Count() != 2 {
+		t.Fatalf("unexpected consumers: got %d created and %d registered, want 2", len(consumers), registry.Count())
+	}
+	// the messages of the shared consumer are dispatched to both of its subscriptions
+	consumers[0].messagesHandler(stream.ConsumerContext{}, &amqp.Message{Data: [][]byte{[]byte("message")}})
+	if len(received) != 2 || received[0] == received[1] {
+		t.Errorf("unexpected handlers of the shared consumer: %v", received)
+	}
+
+	// the shared consumer is closed with its last subscription
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close returned an error: %v", err)
+	}
+	if closes := consumers[0].getCloses(); closes != 0 || registry.Count() != 2 {
+		t.Errorf("the consumer was released while a subscription is left: %d closes, %d registered", closes, registry.Count())
+	}
+	// a subscription is released once, even when it is closed again
+	_ = first.Close()
+	_ = second.Close()
+	_ = second.Close()
+	if closes := consumers[0].getCloses(); closes != 1 || registry.Count() != 1 {
+		t.Errorf("unexpected release of the consumer: %d closes, %d registered, want 1 and 1", closes, registry.Count())
+	}
+
+	_ = other.Close()
+	if closes := consumers[1].getCloses(); closes != 1 || registry.Count() != 0 {
+		t.Errorf("unexpected release of the other consumer: %d closes, %d registered, want 1 and 0", closes, registry.Count())
+	}
+}
+
+func TestConsumerRegistryConcurrentSubscribe(t *testing.T) {
+	registry, fakes := newFakeRegistry()
+	subscriptions := make([]*ConsumerSubscription, 20)
+	var group sync.WaitGroup
+	for index := range subscriptions {
+		group.Add(1)
+		go func(index int) {
+			defer group.Done()
+			subscription, err := registry.Subscribe(nil, &StreamOptions{StreamName: "rabbitmq.stream"}, func(stream.ConsumerContext, *amqp.Message) {})
+			if err != nil {
+				t.Errorf("Subscribe returned an error: %v", err)
+				return
+			}
+			subscriptions[index] = subscription
+		}(index)
+	}
+	group.Wait()
+
+	consumers := fakes.created()
+	if len(consumers) != 1 {
+		t.Fatalf("unexpected consumers: got %d, want 1", len(consumers))
+	}
+	for _, subscription := range subscriptions {
+		group.Add(1)
+		go func(subscription *ConsumerSubscription) {
+			defer group.Done()
+			_ = subscription.Close()
+		}(subscription)
+	}
+	group.Wait()
+	if closes := consumers[0].getCloses(); closes != 1 || registry.Count() != 0 {
+		t.Errorf("unexpected release of the consumer: %d closes, %d registered, want 1 and 0", closes, registry.Count())
+	}
+}
+
+func TestConsumerRegistryClosedByBroker(t *testing.T) {
+	registry, fakes := newFakeRegistry()
+	first := subscribe(t, registry, "rabbitmq.stream", func(stream.ConsumerContext, *amqp.Message) {})
+	fakes.created()[0].closeByBroker()
+	<-first.NotifyClose()
+
+	// the next subscription doesn't join the closed consumer
+	second := subscribe(t, registry, "rabbitmq.stream", func(stream.ConsumerContext, *amqp.Message) {})
+	if consumers := fakes.created(); len(consumers) != 2 {
+		t.Errorf("unexpected consumers: got %d, want 2", len(consumers))
+	}
+	_ = first.Close()
+	_ = second.Close()
+	if registry.Count() != 0 {
+		t.Errorf("unexpected registered consumers: got %d, want 0", registry.Count())
+	}
+}
+
+// waitForPending waits until the registry has a consumer that is being created.
+func waitForPending(t *testing.T, registry *ConsumerRegistry) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for registry.Count() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no consumer is being created")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConsumerRegistryPendingConsumerFails(t *testing.T) {
+	registry, fakes := newFakeRegistry()
+	fakes.release = make(chan struct{})
+	fakes.err = errors.New("stream does not exist")
+
+	errs := make(chan error, 2)
+	subscribeAsync := func() {
+		_, err := registry.Subscribe(nil, &StreamOptions{StreamName: "rabbitmq.stream"}, func(stream.ConsumerContext, *amqp.Message) {})
+		errs <- err
+	}
+	go subscribeAsync()
+	waitForPending(t, registry)
+	// the second subscription waits for the pending consumer, and gets its error
+	go subscribeAsync()
+	close(fakes.release)
+	for index := 0; index < 2; index += 1 {
+		if err := <-errs; err == nil {
+			t.Errorf("the subscription of a failed consumer succeeded")
+		}
+	}
+	if registry.Count() != 0 {
+		t.Errorf("the failed consumer is still registered")
+	}
+
+	// the failed consumer is not reused by the next subscription
+	fakes.mutex.Lock()
+	fakes.err = nil
+	fakes.mutex.Unlock()
+	subscription := subscribe(t, registry, "rabbitmq.stream", func(stream.ConsumerContext, *amqp.Message) {})
+	if consumers := fakes.created(); len(consumers) != 1 || registry.Count() != 1 {
+		t.Errorf("unexpected consumers: got %d created and %d registered, want 1", len(consumers), registry.Count())
+	}
+	_ = subscription.Close()
+}
+
+func TestConsumerRegistryClosedWhilePending(t *testing.T) {
+	registry, fakes := newFakeRegistry()
+	fakes.release = make(chan struct{})
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := registry.Subscribe(nil, &StreamOptions{StreamName: "rabbitmq.stream"}, func(stream.ConsumerContext, *amqp.Message) {})
+		errs <- err
+	}()
+	waitForPending(t, registry)
+	if err := registry.CloseAll(); err != nil {
+		t.Fatalf("CloseAll returned an error: %v", err)
+	}
+	close(fakes.release)
+
+	if err := <-errs; err == nil {
+		t.Errorf("the subscription of a consumer that was closed while it was created succeeded")
+	}
+	consumers := fakes.created()
+	if len(consumers) != 1 || consumers[0].getCloses() != 1 {
+		t.Errorf("the consumer that was created after CloseAll was not closed")
+	}
+	if registry.Count() != 0 {
+		t.Errorf("unexpected registered consumers: got %d, want 0", registry.Count())
+	}
+}
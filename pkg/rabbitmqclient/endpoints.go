@@ -0,0 +1,171 @@
+package rabbitmqclient
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	stream "github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+const (
+	STREAM_URI_SCHEME     = "rabbitmq-stream"
+	STREAM_TLS_URI_SCHEME = "rabbitmq-stream+tls"
+)
+
+// brokerEndpoint is a single node of the RabbitMQ cluster, or the load balancer in front of it.
+type brokerEndpoint struct {
+	host       string
+	streamPort int
+}
+
+// getEndpoints parses the endpoints of the datasource, which are a host, a host:port or a
+// rabbitmq-stream:// URI with the stream port. Without endpoints, the Host and StreamPort are used.
+func (options *RabbitMQStreamOptions) getEndpoints() ([]brokerEndpoint, error) {
+	if len(options.Endpoints) == 0 {
+		return []brokerEndpoint{{host: options.Host, streamPort: options.StreamPort}}, nil
+	}
+
+	endpoints := make([]brokerEndpoint, 0, len(options.Endpoints))
+	for _, rawEndpoint := range options.Endpoints {
+		endpoint, err := options.parseEndpoint(strings.TrimSpace(rawEndpoint))
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+func (options *RabbitMQStreamOptions) parseEndpoint(rawEndpoint string) (brokerEndpoint, error) {
+	endpoint := brokerEndpoint{host: rawEndpoint, streamPort: options.StreamPort}
+	hostPort := rawEndpoint
+
+	if strings.Contains(rawEndpoint, "://") {
+		endpointURL, err := url.Parse(rawEndpoint)
+		if err != nil {
+			return endpoint, fmt.Errorf("invalid endpoint %q: %w", rawEndpoint, err)
+		}
+		if endpointURL.Scheme != STREAM_URI_SCHEME && endpointURL.Scheme != STREAM_TLS_URI_SCHEME {
+			return endpoint, fmt.Errorf("invalid endpoint %q: the scheme must be %s or %s", rawEndpoint, STREAM_URI_SCHEME, STREAM_TLS_URI_SCHEME)
+		}
+		if (endpointURL.Scheme == STREAM_TLS_URI_SCHEME) != options.IsTLS {
+			return endpoint, fmt.Errorf("invalid endpoint %q: the scheme doesn't match the TLS connection setting", rawEndpoint)
+		}
+		hostPort = endpointURL.Host
+	}
+
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		// the endpoint has no port
+		endpoint.host = hostPort
+		return endpoint, validateEndpointHost(rawEndpoint, endpoint.host)
+	}
+	endpoint.host = host
+	if endpoint.streamPort, err = strconv.Atoi(port); err != nil {
+		return endpoint, fmt.Errorf("invalid endpoint %q: the port must be a number", rawEndpoint)
+	}
+	return endpoint, validateEndpointHost(rawEndpoint, endpoint.host)
+}
+
+func validateEndpointHost(rawEndpoint string, host string) error {
+	if host == "" {
+		return fmt.Errorf("invalid endpoint %q: the host is empty", rawEndpoint)
+	}
+	return nil
+}
+
+func (options *RabbitMQStreamOptions) ValidateEndpoints() error {
+	_, err := options.getEndpoints()
+	return err
+}
+
+// isLoadBalancerMode tells if every connection goes through the endpoint the environment was created
+// with, like a load balancer in front of the nodes. It is the default, since it was the only mode.
+func (options *RabbitMQStreamOptions) isLoadBalancerMode() bool {
+	return options.LoadBalancerMode == nil || *options.LoadBalancerMode
+}
+
+// getRotatedEndpoints returns the endpoints starting from the last endpoint that was connected,
+// so a reconnection tries the healthy node first and fails over to the next ones.
+func (client *RabbitMQStreamClient) getRotatedEndpoints() ([]brokerEndpoint, error) {
+	endpoints, err := client.RabbitMQOptions.getEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	start := int(client.endpointIndex.Load()) % len(endpoints)
+	return append(endpoints[start:], endpoints[:start]...), nil
+}
+
+func (client *RabbitMQStreamClient) setConnectedEndpoint(endpoint brokerEndpoint) {
+	endpoints, err := client.RabbitMQOptions.getEndpoints()
+	if err != nil {
+		return
+	}
+	for endpointIndex := range endpoints {
+		if endpoints[endpointIndex] == endpoint {
+			client.endpointIndex.Store(int64(endpointIndex))
+			return
+		}
+	}
+}
+
+// newEnvironment creates the environment with the first endpoint that accepts the connection. In the load
+// balancer mode every connection goes through that endpoint, otherwise the consumers connect directly to the
+// node of the stream leader or replica they are assigned to.
+func (client *RabbitMQStreamClient) newEnvironment(newEnvOptions func() *stream.EnvironmentOptions) (*stream.Environment, error) {
+	endpoints, err := client.getRotatedEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	options := client.RabbitMQOptions
+
+	if !options.isLoadBalancerMode() {
+		envOptions := newEnvOptions()
+		setBrokers(envOptions, endpoints...)
+		envOptions.IsTLS(options.IsTLS)
+		return stream.NewEnvironment(envOptions)
+	}
+
+	var lastErr error
+	for _, endpoint := range endpoints {
+		envOptions := newEnvOptions()
+		setBrokers(envOptions, endpoint)
+		envOptions.IsTLS(options.IsTLS)
+		envOptions.SetAddressResolver(stream.AddressResolver{
+			Host: endpoint.host,
+			Port: endpoint.streamPort,
+		})
+		env, err := stream.NewEnvironment(envOptions)
+		if err == nil {
+			client.setConnectedEndpoint(endpoint)
+			return env, nil
+		}
+		lastErr = err
+		log.DefaultLogger.Warn("Failed to connect to the RabbitMQ endpoint, trying the next one", "host", endpoint.host, "port", endpoint.streamPort, "error", err)
+	}
+	return nil, lastErr
+}
+
+// setBrokers replaces the brokers of the environment options with the endpoints, every broker keeps
+// the credentials and vhost that were set on the first broker of the environment options.
+func setBrokers(envOptions *stream.EnvironmentOptions, endpoints ...brokerEndpoint) {
+	defaults := &stream.Broker{}
+	if len(envOptions.ConnectionParameters) > 0 {
+		defaults = envOptions.ConnectionParameters[0]
+	}
+	brokers := make([]*stream.Broker, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		brokers = append(brokers, &stream.Broker{
+			Host:     endpoint.host,
+			Port:     strconv.Itoa(endpoint.streamPort),
+			User:     defaults.User,
+			Password: defaults.Password,
+			Vhost:    defaults.Vhost,
+		})
+	}
+	envOptions.ConnectionParameters = brokers
+}
@@ -0,0 +1,117 @@
+package rabbitmqclient
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+func TestGetEndpoints(t *testing.T) {
+	tests := []struct {
+		name              string
+		options           *RabbitMQStreamOptions
+		expectedEndpoints []brokerEndpoint
+		expectError       bool
+	}{
+		{
+			name:              "host and stream port",
+			options:           &RabbitMQStreamOptions{Host: "rabbitmq", StreamPort: 5552},
+			expectedEndpoints: []brokerEndpoint{{host: "rabbitmq", streamPort: 5552}},
+		},
+		{
+			name:    "hosts with and without ports",
+			options: &RabbitMQStreamOptions{StreamPort: 5552, Endpoints: []string{"node-1", " node-2:5553 ", "[::1]:5554"}},
+			expectedEndpoints: []brokerEndpoint{
+				{host: "node-1", streamPort: 5552},
+				{host: "node-2", streamPort: 5553},
+				{host: "::1", streamPort: 5554},
+			},
+		},
+		{
+			name:              "stream URI",
+			options:           &RabbitMQStreamOptions{StreamPort: 5552, Endpoints: []string{"rabbitmq-stream://node-1:5560"}},
+			expectedEndpoints: []brokerEndpoint{{host: "node-1", streamPort: 5560}},
+		},
+		{
+			name:              "TLS stream URI without a port",
+			options:           &RabbitMQStreamOptions{StreamPort: 5551, IsTLS: true, Endpoints: []string{"rabbitmq-stream+tls://node-1"}},
+			expectedEndpoints: []brokerEndpoint{{host: "node-1", streamPort: 5551}},
+		},
+		{
+			name:        "TLS stream URI without a TLS connection",
+			options:     &RabbitMQStreamOptions{StreamPort: 5552, Endpoints: []string{"rabbitmq-stream+tls://node-1:5551"}},
+			expectError: true,
+		},
+		{
+			name:        "AMQP URI",
+			options:     &RabbitMQStreamOptions{StreamPort: 5552, Endpoints: []string{"amqp://node-1:5672"}},
+			expectError: true,
+		},
+		{
+			name:        "port that is not a number",
+			options:     &RabbitMQStreamOptions{StreamPort: 5552, Endpoints: []string{"node-1:stream"}},
+			expectError: true,
+		},
+		{
+			name:        "empty host",
+			options:     &RabbitMQStreamOptions{StreamPort: 5552, Endpoints: []string{":5552"}},
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			endpoints, err := test.options.getEndpoints()
+			if (err != nil) != test.expectError {
+				t.Fatalf("unexpected error: got %v, want an error: %v", err, test.expectError)
+			}
+			if !test.expectError && !reflect.DeepEqual(endpoints, test.expectedEndpoints) {
+				t.Errorf("unexpected endpoints: got %+v, want %+v", endpoints, test.expectedEndpoints)
+			}
+		})
+	}
+}
+
+func TestGetRotatedEndpoints(t *testing.T) {
+	client := NewRabbitMQStreamClient().SetRabbitMQOptions(&RabbitMQStreamOptions{
+		StreamPort: 5552,
+		Endpoints:  []string{"node-1", "node-2", "node-3"},
+	})
+
+	client.setConnectedEndpoint(brokerEndpoint{host: "node-2", streamPort: 5552})
+	endpoints, err := client.getRotatedEndpoints()
+	if err != nil {
+		t.Fatalf("getRotatedEndpoints returned an error: %v", err)
+	}
+	// the last connected endpoint is tried first
+	expectedEndpoints := []brokerEndpoint{
+		{host: "node-2", streamPort: 5552},
+		{host: "node-3", streamPort: 5552},
+		{host: "node-1", streamPort: 5552},
+	}
+	if !reflect.DeepEqual(endpoints, expectedEndpoints) {
+		t.Errorf("unexpected endpoints: got %+v, want %+v", endpoints, expectedEndpoints)
+	}
+}
+
+func TestSetBrokers(t *testing.T) {
+	client := NewRabbitMQStreamClient().SetRabbitMQOptions(&RabbitMQStreamOptions{
+		User:     "grafana",
+		Password: "secret",
+		VHost:    "/monitoring",
+	})
+	newEnvOptions, err := client.getEnvOptionsFactory()
+	if err != nil {
+		t.Fatalf("getEnvOptionsFactory returned an error: %v", err)
+	}
+	envOptions := newEnvOptions()
+	setBrokers(envOptions, brokerEndpoint{host: "node-1", streamPort: 5552}, brokerEndpoint{host: "node-2", streamPort: 5553})
+
+	expectedBrokers := []*stream.Broker{
+		{Host: "node-1", Port: "5552", User: "grafana", Password: "secret", Vhost: "/monitoring"},
+		{Host: "node-2", Port: "5553", User: "grafana", Password: "secret", Vhost: "/monitoring"},
+	}
+	if !reflect.DeepEqual(envOptions.ConnectionParameters, expectedBrokers) {
+		t.Errorf("unexpected brokers: got %+v, want %+v", envOptions.ConnectionParameters, expectedBrokers)
+	}
+}
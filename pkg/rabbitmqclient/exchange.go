@@ -7,8 +7,8 @@ import (
 )
 
 type Exchange interface {
-	CreateExchange(*amqp.Channel) error
-	DisposeExchange(*amqp.Channel) error
+	CreateExchange(TopologyChannel) error
+	DisposeExchange(TopologyChannel) error
 }
 
 type ExchangeOptions struct {
@@ -20,9 +20,12 @@ type ExchangeOptions struct {
 	NoWait                bool   `json:"noWait"`
 	ShouldDisposeExchange bool   `json:"shouldDisposeExchange"`
 	DisposeIfUnused       bool   `json:"disposeIfUnused"`
+	// AlternateExchange receives the messages that can't be routed by the exchange
+	AlternateExchange string                 `json:"alternateExchange"`
+	Arguments         map[string]interface{} `json:"arguments"`
 }
 
-func (exchangeOptions *ExchangeOptions) CreateExchange(ch *amqp.Channel) error {
+func (exchangeOptions *ExchangeOptions) CreateExchange(ch TopologyChannel) error {
 	err := ch.ExchangeDeclare(
 		exchangeOptions.Name,
 		exchangeOptions.Type,
@@ -30,12 +33,36 @@ func (exchangeOptions *ExchangeOptions) CreateExchange(ch *amqp.Channel) error {
 		exchangeOptions.AutoDeleted,
 		exchangeOptions.Internal,
 		exchangeOptions.NoWait,
-		nil, // arguments - not supported at the moment
+		exchangeOptions.getArguments(),
 	)
 	return failOnError(err, fmt.Sprintf("Failed to create the exchange %s", exchangeOptions.Name))
 }
 
-func (exchangeOptions *ExchangeOptions) DisposeExchange(ch *amqp.Channel) error {
+func (exchangeOptions *ExchangeOptions) getArguments() amqp.Table {
+	arguments := toTableOrNil(exchangeOptions.Arguments)
+	if exchangeOptions.AlternateExchange != "" {
+		if arguments == nil {
+			arguments = amqp.Table{}
+		}
+		arguments["alternate-exchange"] = exchangeOptions.AlternateExchange
+	}
+	return arguments
+}
+
+// exists declares the exchange passively, the settings of an existing exchange are not compared.
+func (exchangeOptions *ExchangeOptions) exists(ch TopologyChannel) (bool, error) {
+	return checkExists(ch.ExchangeDeclarePassive(
+		exchangeOptions.Name,
+		exchangeOptions.Type,
+		exchangeOptions.Durable,
+		exchangeOptions.AutoDeleted,
+		exchangeOptions.Internal,
+		false, // no-wait - the broker must answer whether the exchange exists
+		nil,
+	))
+}
+
+func (exchangeOptions *ExchangeOptions) DisposeExchange(ch TopologyChannel) error {
 	if !exchangeOptions.ShouldDisposeExchange {
 		return nil
 	}
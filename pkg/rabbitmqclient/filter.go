@@ -0,0 +1,73 @@
+package rabbitmqclient
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+// StreamFilterOptions asks the broker to only send the chunks that contain messages with one of the
+// filter values (requires RabbitMQ 3.13 or later). A chunk can also contain messages of other values,
+// so the messages are filtered again by the application property that holds the filter value.
+type StreamFilterOptions struct {
+	Values []string `json:"values"`
+	// MatchUnfiltered also sends the messages that were published without a filter value
+	MatchUnfiltered bool `json:"matchUnfiltered"`
+	// Property is the application property that holds the filter value, no message is filtered again when it is empty
+	Property string `json:"property"`
+}
+
+func (filterOptions *StreamFilterOptions) IsEnabled() bool {
+	return filterOptions != nil && len(filterOptions.Values) > 0
+}
+
+func (filterOptions *StreamFilterOptions) Validate() error {
+	if filterOptions == nil {
+		return nil
+	}
+	for _, value := range filterOptions.Values {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("invalid stream filter: the filter values must not be empty")
+		}
+	}
+	if filterOptions.Property != "" && len(filterOptions.Values) == 0 {
+		return fmt.Errorf("invalid stream filter: the property %s requires filter values", filterOptions.Property)
+	}
+	return nil
+}
+
+func (filterOptions *StreamFilterOptions) toConsumerFilter() *stream.ConsumerFilter {
+	return stream.NewConsumerFilter(filterOptions.Values, filterOptions.MatchUnfiltered, filterOptions.postFilter)
+}
+
+// postFilter keeps the messages whose property holds one of the filter values, and the
+// messages without the property when the unfiltered messages are matched too.
+func (filterOptions *StreamFilterOptions) postFilter(message *amqp.Message) bool {
+	if filterOptions.Property == "" {
+		return true
+	}
+	value, exists := message.ApplicationProperties[filterOptions.Property]
+	if !exists || value == nil {
+		return filterOptions.MatchUnfiltered
+	}
+	filterValue := fmt.Sprint(value)
+	for _, expectedValue := range filterOptions.Values {
+		if filterValue == expectedValue {
+			return true
+		}
+	}
+	return false
+}
+
+// String identifies the filter in the consumer key, the order of the values doesn't matter.
+func (filterOptions *StreamFilterOptions) String() string {
+	if !filterOptions.IsEnabled() {
+		return ""
+	}
+	values := append([]string{}, filterOptions.Values...)
+	sort.Strings(values)
+	return fmt.Sprintf("%s=%s (unfiltered: %t)", filterOptions.Property, strings.Join(values, ","), filterOptions.MatchUnfiltered)
+}
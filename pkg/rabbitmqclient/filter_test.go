@@ -0,0 +1,130 @@
+package rabbitmqclient
+
+import (
+	"testing"
+
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+)
+
+func TestPostFilter(t *testing.T) {
+	filterOptions := &StreamFilterOptions{Values: []string{"boiler", "42"}, Property: "device"}
+	unfilteredOptions := &StreamFilterOptions{Values: []string{"boiler"}, Property: "device", MatchUnfiltered: true}
+	tests := []struct {
+		name          string
+		filterOptions *StreamFilterOptions
+		message       *amqp.Message
+		expectedKept  bool
+	}{
+		{
+			name:          "matching application property",
+			filterOptions: filterOptions,
+			message:       &amqp.Message{ApplicationProperties: map[string]interface{}{"device": "boiler"}},
+			expectedKept:  true,
+		},
+		{
+			name:          "matching application property of another type",
+			filterOptions: filterOptions,
+			message:       &amqp.Message{ApplicationProperties: map[string]interface{}{"device": int64(42)}},
+			expectedKept:  true,
+		},
+		{
+			name:          "application property of another value",
+			filterOptions: filterOptions,
+			message:       &amqp.Message{ApplicationProperties: map[string]interface{}{"device": "pump"}},
+			expectedKept:  false,
+		},
+		{
+			name:          "value in another application property",
+			filterOptions: filterOptions,
+			message:       &amqp.Message{ApplicationProperties: map[string]interface{}{"site": "boiler"}},
+			expectedKept:  false,
+		},
+		{
+			name:          "value in the properties only",
+			filterOptions: filterOptions,
+			message:       &amqp.Message{Properties: &amqp.MessageProperties{Subject: "boiler"}},
+			expectedKept:  false,
+		},
+		{
+			name:          "unfiltered message",
+			filterOptions: unfilteredOptions,
+			message:       &amqp.Message{},
+			expectedKept:  true,
+		},
+		{
+			name:          "unfiltered message with a nil value",
+			filterOptions: unfilteredOptions,
+			message:       &amqp.Message{ApplicationProperties: map[string]interface{}{"device": nil}},
+			expectedKept:  true,
+		},
+		{
+			name:          "filtered message when the unfiltered messages are matched",
+			filterOptions: unfilteredOptions,
+			message:       &amqp.Message{ApplicationProperties: map[string]interface{}{"device": "pump"}},
+			expectedKept:  false,
+		},
+		{
+			name:          "no property",
+			filterOptions: &StreamFilterOptions{Values: []string{"boiler"}},
+			message:       &amqp.Message{ApplicationProperties: map[string]interface{}{"device": "pump"}},
+			expectedKept:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if kept := test.filterOptions.postFilter(test.message); kept != test.expectedKept {
+				t.Errorf("unexpected post filter: got %v, want %v", kept, test.expectedKept)
+			}
+		})
+	}
+}
+
+func TestFilterString(t *testing.T) {
+	tests := []struct {
+		name           string
+		filterOptions  *StreamFilterOptions
+		expectedString string
+	}{
+		{name: "no filter", filterOptions: nil, expectedString: ""},
+		{name: "empty filter", filterOptions: &StreamFilterOptions{Property: "device"}, expectedString: ""},
+		{
+			name:           "sorted values",
+			filterOptions:  &StreamFilterOptions{Values: []string{"pump", "boiler"}, Property: "device"},
+			expectedString: "device=boiler,pump (unfiltered: false)",
+		},
+		{
+			name:           "unfiltered messages",
+			filterOptions:  &StreamFilterOptions{Values: []string{"boiler"}, MatchUnfiltered: true},
+			expectedString: "=boiler (unfiltered: true)",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actualString := test.filterOptions.String(); actualString != test.expectedString {
+				t.Errorf("unexpected filter string: got %q, want %q", actualString, test.expectedString)
+			}
+		})
+	}
+}
+
+func TestValidateFilter(t *testing.T) {
+	tests := []struct {
+		name          string
+		filterOptions *StreamFilterOptions
+		expectError   bool
+	}{
+		{name: "no filter", filterOptions: nil},
+		{name: "empty filter", filterOptions: &StreamFilterOptions{}},
+		{name: "values", filterOptions: &StreamFilterOptions{Values: []string{"boiler"}, Property: "device"}},
+		{name: "empty value", filterOptions: &StreamFilterOptions{Values: []string{"boiler", " "}}, expectError: true},
+		{name: "property without values", filterOptions: &StreamFilterOptions{Property: "device"}, expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.filterOptions.Validate()
+			if (err != nil) != test.expectError {
+				t.Errorf("unexpected validation error: got %v, want an error: %v", err, test.expectError)
+			}
+		})
+	}
+}
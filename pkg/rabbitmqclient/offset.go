@@ -0,0 +1,175 @@
+package rabbitmqclient
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+const (
+	OFFSET_FIRST         = "first"
+	OFFSET_LAST          = "last"
+	OFFSET_NEXT          = "next"
+	OFFSET_OFFSET        = "offset"
+	OFFSET_TIMESTAMP     = "timestamp"
+	OFFSET_RELATIVE_TIME = "relativeTime"
+	OFFSET_LAST_CONSUMED = "lastConsumed"
+)
+
+// OffsetOptions selects where a consumer starts to consume the stream.
+type OffsetOptions struct {
+	Type string `json:"type"`
+	// Offset is the absolute offset of the OFFSET_OFFSET type
+	Offset int64 `json:"offset,omitempty"`
+	// Timestamp is the epoch time in milliseconds of the OFFSET_TIMESTAMP type
+	Timestamp int64 `json:"timestamp,omitempty"`
+	// RelativeTime is how far back from now the OFFSET_RELATIVE_TIME type starts, like "15m"
+	RelativeTime string `json:"relativeTime,omitempty"`
+}
+
+func NewOffsetOptions(offsetFromStart bool) *OffsetOptions {
+	if offsetFromStart {
+		return &OffsetOptions{Type: OFFSET_FIRST}
+	}
+	return &OffsetOptions{Type: OFFSET_LAST}
+}
+
+func (offsetOptions *OffsetOptions) Validate() error {
+	switch offsetOptions.Type {
+	case OFFSET_FIRST, OFFSET_LAST, OFFSET_NEXT, OFFSET_LAST_CONSUMED:
+		return nil
+	case OFFSET_OFFSET:
+		if offsetOptions.Offset < 0 {
+			return fmt.Errorf("invalid offset %d: the offset must not be negative", offsetOptions.Offset)
+		}
+		return nil
+	case OFFSET_TIMESTAMP:
+		if offsetOptions.Timestamp <= 0 {
+			return fmt.Errorf("invalid timestamp %d: the timestamp must be a positive epoch time in milliseconds", offsetOptions.Timestamp)
+		}
+		return nil
+	case OFFSET_RELATIVE_TIME:
+		_, err := offsetOptions.getRelativeTime()
+		return err
+	default:
+		return fmt.Errorf("unknown offset type %q: the offset type must be one of %s, %s, %s, %s, %s, %s or %s",
+			offsetOptions.Type,
+			OFFSET_FIRST, OFFSET_LAST, OFFSET_NEXT, OFFSET_OFFSET, OFFSET_TIMESTAMP, OFFSET_RELATIVE_TIME, OFFSET_LAST_CONSUMED,
+		)
+	}
+}
+
+// ToOffsetSpecification returns the offset specification of the stream client, relative times are
+// resolved against the current time, so they must be resolved again for every new consumer.
+func (offsetOptions *OffsetOptions) ToOffsetSpecification() (stream.OffsetSpecification, error) {
+	offsetSpecification := stream.OffsetSpecification{}
+	if err := offsetOptions.Validate(); err != nil {
+		return offsetSpecification, err
+	}
+	switch offsetOptions.Type {
+	case OFFSET_FIRST:
+		return offsetSpecification.First(), nil
+	case OFFSET_LAST:
+		return offsetSpecification.Last(), nil
+	case OFFSET_NEXT:
+		return offsetSpecification.Next(), nil
+	case OFFSET_OFFSET:
+		return offsetSpecification.Offset(offsetOptions.Offset), nil
+	case OFFSET_TIMESTAMP:
+		return offsetSpecification.Timestamp(offsetOptions.Timestamp), nil
+	case OFFSET_RELATIVE_TIME:
+		relativeTime, _ := offsetOptions.getRelativeTime()
+		return offsetSpecification.Timestamp(time.Now().Add(-relativeTime).UnixMilli()), nil
+	default:
+		return offsetSpecification.LastConsumed(), nil
+	}
+}
+
+func (offsetOptions *OffsetOptions) String() string {
+	switch offsetOptions.Type {
+	case OFFSET_OFFSET:
+		return fmt.Sprintf("%s: %d", offsetOptions.Type, offsetOptions.Offset)
+	case OFFSET_TIMESTAMP:
+		return fmt.Sprintf("%s: %d", offsetOptions.Type, offsetOptions.Timestamp)
+	case OFFSET_RELATIVE_TIME:
+		return fmt.Sprintf("%s: %s", offsetOptions.Type, offsetOptions.RelativeTime)
+	default:
+		return offsetOptions.Type
+	}
+}
+
+func (offsetOptions *OffsetOptions) getRelativeTime() (time.Duration, error) {
+	relativeTime, err := time.ParseDuration(offsetOptions.RelativeTime)
+	if err != nil {
+		return 0, fmt.Errorf("invalid relative time %q: the relative time must be a duration like 15m or 2h: %w", offsetOptions.RelativeTime, err)
+	}
+	if relativeTime <= 0 {
+		return 0, fmt.Errorf("invalid relative time %q: the relative time must be positive", offsetOptions.RelativeTime)
+	}
+	return relativeTime, nil
+}
+
+const (
+	OFFSET_STORAGE_NONE     = "none"
+	OFFSET_STORAGE_AUTO     = "auto"
+	OFFSET_STORAGE_PERIODIC = "periodic"
+
+	DEFAULT_OFFSET_STORAGE_MESSAGE_COUNT    = 1000
+	DEFAULT_OFFSET_STORAGE_INTERVAL_SECONDS = 5
+)
+
+// OffsetStorageOptions stores the offset of named consumers in the RabbitMQ, so a new
+// consumer with the same name resumes after the last stored offset instead of its configured offset.
+type OffsetStorageOptions struct {
+	// Mode is OFFSET_STORAGE_AUTO to let the stream client store the offset every MessageCount
+	// messages or IntervalSeconds, or OFFSET_STORAGE_PERIODIC to store it every IntervalSeconds.
+	Mode            string `json:"mode"`
+	MessageCount    int    `json:"messageCount,omitempty"`
+	IntervalSeconds int    `json:"intervalSeconds,omitempty"`
+}
+
+func (offsetStorageOptions *OffsetStorageOptions) Validate() error {
+	switch offsetStorageOptions.Mode {
+	case "", OFFSET_STORAGE_NONE, OFFSET_STORAGE_AUTO, OFFSET_STORAGE_PERIODIC:
+	default:
+		return fmt.Errorf("unknown offset storage mode %q: the mode must be one of %s, %s or %s",
+			offsetStorageOptions.Mode, OFFSET_STORAGE_NONE, OFFSET_STORAGE_AUTO, OFFSET_STORAGE_PERIODIC)
+	}
+	if offsetStorageOptions.MessageCount < 0 {
+		return fmt.Errorf("invalid offset storage message count %d: the message count must not be negative", offsetStorageOptions.MessageCount)
+	}
+	if offsetStorageOptions.IntervalSeconds < 0 {
+		return fmt.Errorf("invalid offset storage interval %d: the interval must not be negative", offsetStorageOptions.IntervalSeconds)
+	}
+	return nil
+}
+
+func (offsetStorageOptions *OffsetStorageOptions) IsEnabled() bool {
+	return offsetStorageOptions != nil &&
+		(offsetStorageOptions.Mode == OFFSET_STORAGE_AUTO || offsetStorageOptions.Mode == OFFSET_STORAGE_PERIODIC)
+}
+
+func (offsetStorageOptions *OffsetStorageOptions) IsPeriodic() bool {
+	return offsetStorageOptions != nil && offsetStorageOptions.Mode == OFFSET_STORAGE_PERIODIC
+}
+
+func (offsetStorageOptions *OffsetStorageOptions) getMessageCount() int {
+	if offsetStorageOptions.MessageCount <= 0 {
+		return DEFAULT_OFFSET_STORAGE_MESSAGE_COUNT
+	}
+	return offsetStorageOptions.MessageCount
+}
+
+func (offsetStorageOptions *OffsetStorageOptions) GetInterval() time.Duration {
+	if offsetStorageOptions.IntervalSeconds <= 0 {
+		return DEFAULT_OFFSET_STORAGE_INTERVAL_SECONDS * time.Second
+	}
+	return time.Duration(offsetStorageOptions.IntervalSeconds) * time.Second
+}
+
+func (offsetStorageOptions *OffsetStorageOptions) toAutoCommitStrategy() *stream.AutoCommitStrategy {
+	return stream.NewAutoCommitStrategy().
+		SetCountBeforeStorage(offsetStorageOptions.getMessageCount()).
+		SetFlushInterval(offsetStorageOptions.GetInterval())
+}
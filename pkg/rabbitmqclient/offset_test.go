@@ -0,0 +1,240 @@
+package rabbitmqclient
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+func TestValidateOffset(t *testing.T) {
+	tests := []struct {
+		name          string
+		offsetOptions *OffsetOptions
+		expectError   bool
+	}{
+		{name: "first", offsetOptions: &OffsetOptions{Type: OFFSET_FIRST}},
+		{name: "last consumed", offsetOptions: &OffsetOptions{Type: OFFSET_LAST_CONSUMED}},
+		{name: "offset", offsetOptions: &OffsetOptions{Type: OFFSET_OFFSET, Offset: 0}},
+		{name: "negative offset", offsetOptions: &OffsetOptions{Type: OFFSET_OFFSET, Offset: -1}, expectError: true},
+		{name: "timestamp", offsetOptions: &OffsetOptions{Type: OFFSET_TIMESTAMP, Timestamp: 1704067200000}},
+		{name: "missing timestamp", offsetOptions: &OffsetOptions{Type: OFFSET_TIMESTAMP}, expectError: true},
+		{name: "relative time", offsetOptions: &OffsetOptions{Type: OFFSET_RELATIVE_TIME, RelativeTime: "15m"}},
+		{name: "invalid relative time", offsetOptions: &OffsetOptions{Type: OFFSET_RELATIVE_TIME, RelativeTime: "15 minutes"}, expectError: true},
+		{name: "negative relative time", offsetOptions: &OffsetOptions{Type: OFFSET_RELATIVE_TIME, RelativeTime: "-1h"}, expectError: true},
+		{name: "unknown type", offsetOptions: &OffsetOptions{Type: "middle"}, expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.offsetOptions.Validate()
+			if (err != nil) != test.expectError {
+				t.Errorf("unexpected validation error: got %v, want an error: %v", err, test.expectError)
+			}
+		})
+	}
+}
+
+func TestToOffsetSpecification(t *testing.T) {
+	offsetSpecification := stream.OffsetSpecification{}
+	tests := []struct {
+		name                        string
+		offsetOptions               *OffsetOptions
+		expectedOffsetSpecification stream.OffsetSpecification
+	}{
+		{name: "first", offsetOptions: NewOffsetOptions(true), expectedOffsetSpecification: offsetSpecification.First()},
+		{name: "last", offsetOptions: NewOffsetOptions(false), expectedOffsetSpecification: offsetSpecification.Last()},
+		{name: "next", offsetOptions: &OffsetOptions{Type: OFFSET_NEXT}, expectedOffsetSpecification: offsetSpecification.Next()},
+		{name: "offset", offsetOptions: &OffsetOptions{Type: OFFSET_OFFSET, Offset: 42}, expectedOffsetSpecification: offsetSpecification.Offset(42)},
+		{
+			name:                        "timestamp",
+			offsetOptions:               &OffsetOptions{Type: OFFSET_TIMESTAMP, Timestamp: 1704067200000},
+			expectedOffsetSpecification: offsetSpecification.Timestamp(1704067200000),
+		},
+		{name: "last consumed", offsetOptions: &OffsetOptions{Type: OFFSET_LAST_CONSUMED}, expectedOffsetSpecification: offsetSpecification.LastConsumed()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actualOffsetSpecification, err := test.offsetOptions.ToOffsetSpecification()
+			if err != nil {
+				t.Fatalf("ToOffsetSpecification returned an error: %v", err)
+			}
+			if !reflect.DeepEqual(actualOffsetSpecification, test.expectedOffsetSpecification) {
+				t.Errorf("unexpected offset specification: got %v, want %v", actualOffsetSpecification, test.expectedOffsetSpecification)
+			}
+		})
+	}
+}
+
+func TestToOffsetSpecificationRelativeTime(t *testing.T) {
+	offsetOptions := &OffsetOptions{Type: OFFSET_RELATIVE_TIME, RelativeTime: "15m"}
+	before := time.Now().Add(-15 * time.Minute).UnixMilli()
+	actualOffsetSpecification, err := offsetOptions.ToOffsetSpecification()
+	if err != nil {
+		t.Fatalf("ToOffsetSpecification returned an error: %v", err)
+	}
+	after := time.Now().Add(-15 * time.Minute).UnixMilli()
+
+	// the relative time is resolved against the current time, which is between before and after
+	var timestamp int64
+	if _, err := fmt.Sscanf(actualOffsetSpecification.String(), "time-stamp, value: %d", &timestamp); err != nil {
+		t.Fatalf("unexpected offset specification: %v", actualOffsetSpecification)
+	}
+	if timestamp < before || timestamp > after {
+		t.Errorf("unexpected timestamp: got %d, want between %d and %d", timestamp, before, after)
+	}
+}
+
+func TestToOffsetSpecificationInvalid(t *testing.T) {
+	offsetOptions := &OffsetOptions{Type: OFFSET_OFFSET, Offset: -1}
+	if _, err := offsetOptions.ToOffsetSpecification(); err == nil {
+		t.Errorf("the offset specification of an invalid offset was returned")
+	}
+}
+
+func TestValidateOffsetStorage(t *testing.T) {
+	tests := []struct {
+		name                 string
+		offsetStorageOptions *OffsetStorageOptions
+		expectError          bool
+		expectedEnabled      bool
+		expectedInterval     time.Duration
+	}{
+		{
+			name:                 "no mode",
+			offsetStorageOptions: &OffsetStorageOptions{},
+			expectedInterval:     DEFAULT_OFFSET_STORAGE_INTERVAL_SECONDS * time.Second,
+		},
+		{
+			name:                 "auto",
+			offsetStorageOptions: &OffsetStorageOptions{Mode: OFFSET_STORAGE_AUTO, IntervalSeconds: 10},
+			expectedEnabled:      true,
+			expectedInterval:     10 * time.Second,
+		},
+		{
+			name:                 "periodic",
+			offsetStorageOptions: &OffsetStorageOptions{Mode: OFFSET_STORAGE_PERIODIC},
+			expectedEnabled:      true,
+			expectedInterval:     DEFAULT_OFFSET_STORAGE_INTERVAL_SECONDS * time.Second,
+		},
+		{
+			name:                 "unknown mode",
+			offsetStorageOptions: &OffsetStorageOptions{Mode: "manual"},
+			expectError:          true,
+		},
+		{
+			name:                 "negative message count",
+			offsetStorageOptions: &OffsetStorageOptions{Mode: OFFSET_STORAGE_AUTO, MessageCount: -1},
+			expectError:          true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.offsetStorageOptions.Validate()
+			if (err != nil) != test.expectError {
+				t.Fatalf("unexpected validation error: got %v, want an error: %v", err, test.expectError)
+			}
+			if test.expectError {
+				return
+			}
+			if enabled := test.offsetStorageOptions.IsEnabled(); enabled != test.expectedEnabled {
+				t.Errorf("unexpected enabled: got %v, want %v", enabled, test.expectedEnabled)
+			}
+			if interval := test.offsetStorageOptions.GetInterval(); interval != test.expectedInterval {
+				t.Errorf("unexpected interval: got %v, want %v", interval, test.expectedInterval)
+			}
+		})
+	}
+}
+
+func TestResumeAfterStoredOffset(t *testing.T) {
+	offsetSpecification := stream.OffsetSpecification{}
+	streamOptions := &StreamOptions{StreamName: "rabbitmq.stream", OffsetFromStart: true}
+	tests := []struct {
+		name                        string
+		storedOffset                int64
+		err                         error
+		expectedOffsetSpecification stream.OffsetSpecification
+	}{
+		{name: "stored offset", storedOffset: 41, expectedOffsetSpecification: offsetSpecification.Offset(42)},
+		{name: "stored first offset", storedOffset: 0, expectedOffsetSpecification: offsetSpecification.Offset(1)},
+		{name: "nothing stored", err: stream.OffsetNotFoundError, expectedOffsetSpecification: offsetSpecification.First()},
+		{name: "failed query", err: errors.New("timeout"), expectedOffsetSpecification: offsetSpecification.First()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actualOffsetSpecification, err := streamOptions.resumeAfterStoredOffset(test.storedOffset, test.err)
+			if err != nil {
+				t.Fatalf("resumeAfterStoredOffset returned an error: %v", err)
+			}
+			if !reflect.DeepEqual(actualOffsetSpecification, test.expectedOffsetSpecification) {
+				t.Errorf("unexpected offset specification: got %v, want %v", actualOffsetSpecification, test.expectedOffsetSpecification)
+			}
+		})
+	}
+}
+
+func TestResumeWithoutOffsetStorage(t *testing.T) {
+	// the stored offset is not queried, so no environment is needed
+	streamOptions := &StreamOptions{StreamName: "rabbitmq.stream", OffsetOptions: &OffsetOptions{Type: OFFSET_OFFSET, Offset: 7}}
+	actualOffsetSpecification, err := streamOptions.getResumeOffsetSettings(nil)
+	if err != nil {
+		t.Fatalf("getResumeOffsetSettings returned an error: %v", err)
+	}
+	if expectedOffsetSpecification := (stream.OffsetSpecification{}).Offset(7); !reflect.DeepEqual(actualOffsetSpecification, expectedOffsetSpecification) {
+		t.Errorf("unexpected offset specification: got %v, want %v", actualOffsetSpecification, expectedOffsetSpecification)
+	}
+}
+
+func TestResumeAfter(t *testing.T) {
+	offsetSpecification := stream.OffsetSpecification{}
+	streamOptions := &StreamOptions{
+		StreamName:      "rabbitmq.stream",
+		OffsetFromStart: true,
+		OffsetStorage:   &OffsetStorageOptions{Mode: OFFSET_STORAGE_AUTO},
+	}
+	tests := []struct {
+		name                        string
+		lastDeliveredOffset         int64
+		storedOffset                int64
+		storedOffsetErr             error
+		expectedOffsetSpecification stream.OffsetSpecification
+	}{
+		{
+			name:                        "first offset delivered",
+			lastDeliveredOffset:         0,
+			storedOffsetErr:             stream.OffsetNotFoundError,
+			expectedOffsetSpecification: offsetSpecification.Offset(1),
+		},
+		{
+			name:                        "delivered after the stored offset",
+			lastDeliveredOffset:         20,
+			storedOffset:                10,
+			expectedOffsetSpecification: offsetSpecification.Offset(21),
+		},
+		{
+			name:                        "stored after the delivered offset",
+			lastDeliveredOffset:         20,
+			storedOffset:                30,
+			expectedOffsetSpecification: offsetSpecification.Offset(31),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resumeOptions := streamOptions.ResumeAfter(test.lastDeliveredOffset)
+			actualOffsetSpecification, err := resumeOptions.resumeAfterStoredOffset(test.storedOffset, test.storedOffsetErr)
+			if err != nil {
+				t.Fatalf("resumeAfterStoredOffset returned an error: %v", err)
+			}
+			if !reflect.DeepEqual(actualOffsetSpecification, test.expectedOffsetSpecification) {
+				t.Errorf("unexpected offset specification: got %v, want %v", actualOffsetSpecification, test.expectedOffsetSpecification)
+			}
+		})
+	}
+	// the stream options of the datasource are not changed
+	if offsetOptions := streamOptions.GetOffsetOptions(); offsetOptions.Type != OFFSET_FIRST {
+		t.Errorf("unexpected offset of the resumed stream options: %+v", offsetOptions)
+	}
+}
@@ -0,0 +1,137 @@
+package rabbitmqclient
+
+import (
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	QUEUE_TYPE_CLASSIC = "classic"
+	QUEUE_TYPE_QUORUM  = "quorum"
+	QUEUE_TYPE_STREAM  = "stream"
+
+	QUEUE_OVERFLOW_DROP_HEAD          = "drop-head"
+	QUEUE_OVERFLOW_REJECT_PUBLISH     = "reject-publish"
+	QUEUE_OVERFLOW_REJECT_PUBLISH_DLX = "reject-publish-dlx"
+)
+
+type Queue interface {
+	CreateQueue(TopologyChannel) error
+	DisposeQueue(TopologyChannel) error
+}
+
+// QueueOptions declares a queue of the topology. The x-arguments that have their own field are
+// merged into Arguments, which can hold any other argument of the queue.
+type QueueOptions struct {
+	Name                 string                 `json:"name"`
+	Type                 string                 `json:"type"`
+	Durable              bool                   `json:"durable"`
+	AutoDeleted          bool                   `json:"autoDeleted"`
+	Exclusive            bool                   `json:"exclusive"`
+	NoWait               bool                   `json:"noWait"`
+	MessageTTL           int64                  `json:"messageTtl"`
+	MaxLength            int64                  `json:"maxLength"`
+	MaxLengthBytes       int64                  `json:"maxLengthBytes"`
+	DeadLetterExchange   string                 `json:"deadLetterExchange"`
+	DeadLetterRoutingKey string                 `json:"deadLetterRoutingKey"`
+	Overflow             string                 `json:"overflow"`
+	Arguments            map[string]interface{} `json:"arguments"`
+	ShouldDisposeQueue   bool                   `json:"shouldDisposeQueue"`
+	DisposeIfUnused      bool                   `json:"disposeIfUnused"`
+	DisposeIfEmpty       bool                   `json:"disposeIfEmpty"`
+}
+
+func (queueOptions *QueueOptions) Validate() error {
+	if queueOptions.Name == "" {
+		// the broker generates a new name for every declare of a queue without a name, so it would never be found again
+		return fmt.Errorf("invalid queue: the name of the queue must not be empty")
+	}
+	switch queueOptions.Type {
+	case "", QUEUE_TYPE_CLASSIC:
+	case QUEUE_TYPE_QUORUM, QUEUE_TYPE_STREAM:
+		if !queueOptions.Durable || queueOptions.AutoDeleted || queueOptions.Exclusive {
+			return fmt.Errorf("invalid queue %s: a %s queue must be durable, and can't be auto deleted or exclusive", queueOptions.Name, queueOptions.Type)
+		}
+	default:
+		return fmt.Errorf("invalid queue %s: unknown queue type %q, the type must be one of %s, %s or %s",
+			queueOptions.Name, queueOptions.Type, QUEUE_TYPE_CLASSIC, QUEUE_TYPE_QUORUM, QUEUE_TYPE_STREAM)
+	}
+	switch queueOptions.Overflow {
+	case "", QUEUE_OVERFLOW_DROP_HEAD, QUEUE_OVERFLOW_REJECT_PUBLISH, QUEUE_OVERFLOW_REJECT_PUBLISH_DLX:
+	default:
+		return fmt.Errorf("invalid queue %s: unknown overflow %q, the overflow must be one of %s, %s or %s",
+			queueOptions.Name, queueOptions.Overflow, QUEUE_OVERFLOW_DROP_HEAD, QUEUE_OVERFLOW_REJECT_PUBLISH, QUEUE_OVERFLOW_REJECT_PUBLISH_DLX)
+	}
+	if queueOptions.MessageTTL < 0 || queueOptions.MaxLength < 0 || queueOptions.MaxLengthBytes < 0 {
+		return fmt.Errorf("invalid queue %s: the message TTL and max length must not be negative", queueOptions.Name)
+	}
+	return nil
+}
+
+func (queueOptions *QueueOptions) getArguments() amqp.Table {
+	arguments := toTable(queueOptions.Arguments)
+	if queueOptions.Type != "" {
+		arguments[amqp.QueueTypeArg] = queueOptions.Type
+	}
+	if queueOptions.MessageTTL > 0 {
+		arguments[amqp.QueueMessageTTLArg] = queueOptions.MessageTTL
+	}
+	if queueOptions.MaxLength > 0 {
+		arguments[amqp.QueueMaxLenArg] = queueOptions.MaxLength
+	}
+	if queueOptions.MaxLengthBytes > 0 {
+		arguments[amqp.QueueMaxLenBytesArg] = queueOptions.MaxLengthBytes
+	}
+	if queueOptions.DeadLetterExchange != "" {
+		arguments["x-dead-letter-exchange"] = queueOptions.DeadLetterExchange
+	}
+	if queueOptions.DeadLetterRoutingKey != "" {
+		arguments["x-dead-letter-routing-key"] = queueOptions.DeadLetterRoutingKey
+	}
+	if queueOptions.Overflow != "" {
+		arguments[amqp.QueueOverflowArg] = queueOptions.Overflow
+	}
+	if len(arguments) == 0 {
+		return nil
+	}
+	return arguments
+}
+
+func (queueOptions *QueueOptions) CreateQueue(ch TopologyChannel) error {
+	_, err := ch.QueueDeclare(
+		queueOptions.Name,
+		queueOptions.Durable,
+		queueOptions.AutoDeleted,
+		queueOptions.Exclusive,
+		queueOptions.NoWait,
+		queueOptions.getArguments(),
+	)
+	return failOnError(err, fmt.Sprintf("Failed to create the queue %s", queueOptions.Name))
+}
+
+// exists declares the queue passively, the settings and the arguments of an existing queue are not compared.
+func (queueOptions *QueueOptions) exists(ch TopologyChannel) (bool, error) {
+	_, err := ch.QueueDeclarePassive(
+		queueOptions.Name,
+		queueOptions.Durable,
+		queueOptions.AutoDeleted,
+		queueOptions.Exclusive,
+		false, // no-wait - the broker must answer whether the queue exists
+		nil,
+	)
+	return checkExists(err)
+}
+
+func (queueOptions *QueueOptions) DisposeQueue(ch TopologyChannel) error {
+	if !queueOptions.ShouldDisposeQueue {
+		return nil
+	}
+	_, err := ch.QueueDelete(
+		queueOptions.Name,
+		queueOptions.DisposeIfUnused,
+		queueOptions.DisposeIfEmpty,
+		queueOptions.NoWait,
+	)
+	return failOnError(err, fmt.Sprintf("Failed to delete the queue %s", queueOptions.Name))
+}
@@ -0,0 +1,212 @@
+package rabbitmqclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// QueueConsumerOptions consumes a classic or quorum queue over AMQP instead of a stream. The messages
+// of an existing queue are taken from its other consumers, so consuming it must be allowed by TakeMessages.
+// Otherwise a queue is declared for the consumer only, exclusive and auto-deleted, and bound to an exchange
+// to get a copy of its messages.
+type QueueConsumerOptions struct {
+	QueueName     string `json:"queueName"`
+	TakeMessages  bool   `json:"takeMessages"`
+	Exchange      string `json:"exchange"`
+	RoutingKey    string `json:"routingKey"`
+	PrefetchCount int    `json:"prefetchCount"`
+}
+
+// QueueMessageHandler handles a message that was delivered by a queue, it is acknowledged once delivered.
+type QueueMessageHandler func(delivery amqp.Delivery)
+
+// QueueSubscription is the consumer of a queue, with its own AMQP connection.
+type QueueSubscription struct {
+	conn      *amqp.Connection
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (queueOptions *QueueConsumerOptions) IsEnabled() bool {
+	return queueOptions != nil && (queueOptions.QueueName != "" || queueOptions.Exchange != "")
+}
+
+func (queueOptions *QueueConsumerOptions) Validate() error {
+	if queueOptions == nil {
+		return nil
+	}
+	if queueOptions.QueueName != "" && queueOptions.Exchange != "" {
+		return fmt.Errorf("invalid queue consumer: either a queue name or an exchange must be set, not both")
+	}
+	if queueOptions.QueueName != "" && !queueOptions.TakeMessages {
+		return fmt.Errorf("invalid queue consumer: the messages of the queue %s would be taken from its other consumers, "+
+			"allow it with takeMessages or set an exchange to get a copy of its messages", queueOptions.QueueName)
+	}
+	if queueOptions.PrefetchCount < 0 {
+		return fmt.Errorf("invalid queue consumer: the prefetch count must not be negative")
+	}
+	return nil
+}
+
+func (queueOptions *QueueConsumerOptions) getQueueDescription() string {
+	if queueOptions.Exchange != "" {
+		return fmt.Sprintf("(Exchange: %s ; RoutingKey: %s)", queueOptions.Exchange, queueOptions.RoutingKey)
+	}
+	return queueOptions.QueueName
+}
+
+// ConsumeQueue consumes the queue until the subscription is closed or its AMQP connection is lost.
+func (client *RabbitMQStreamClient) ConsumeQueue(queueOptions *QueueConsumerOptions, messageHandler QueueMessageHandler) (*QueueSubscription, error) {
+	conn, err := client.createAmqpConnection()
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := queueOptions.consume(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	subscription := &QueueSubscription{
+		conn:   conn,
+		closed: make(chan struct{}),
+	}
+	client.addQueueSubscription(subscription)
+	go func() {
+		for delivery := range deliveries {
+			messageHandler(delivery)
+		}
+		log.DefaultLogger.Debug("Queue consumer was closed", "queue", queueOptions.getQueueDescription())
+		client.removeQueueSubscription(subscription)
+		subscription.markClosed()
+	}()
+	return subscription, nil
+}
+
+// ReconsumeQueue consumes the queue again once its AMQP connection was lost. It waits with the backoff of the
+// reconnections between the attempts, until one succeeds, the reconnect limits are reached, the client is
+// disposed or the context is done. The environment of the stream is not needed, so it is not reconnected.
+func (client *RabbitMQStreamClient) ReconsumeQueue(ctx context.Context, queueOptions *QueueConsumerOptions, messageHandler QueueMessageHandler) (*QueueSubscription, error) {
+	reconnectOptions := client.getReconnectOptions()
+	startedAt := time.Now()
+	var lastErr error
+	for attempt := 1; ; attempt += 1 {
+		delay := reconnectOptions.getDelay(attempt)
+		if reconnectOptions.shouldGiveUp(attempt, startedAt, delay) {
+			return nil, fmt.Errorf("failed to consume the queue %s again after %d attempts: %w", queueOptions.getQueueDescription(), attempt-1, lastErr)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-client.disposed:
+			return nil, ErrClientDisposed
+		case <-time.After(delay):
+		}
+
+		subscription, err := client.ConsumeQueue(queueOptions, messageHandler)
+		if err == nil {
+			log.DefaultLogger.Info("Consuming the queue again", "queue", queueOptions.getQueueDescription(), "attempts", attempt)
+			return subscription, nil
+		}
+		lastErr = err
+		log.DefaultLogger.Warn("Failed to consume the queue again", "queue", queueOptions.getQueueDescription(), "attempt", attempt, "error", err)
+	}
+}
+
+func (queueOptions *QueueConsumerOptions) consume(conn *amqp.Connection) (<-chan amqp.Delivery, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if queueOptions.PrefetchCount > 0 {
+		if err := ch.Qos(queueOptions.PrefetchCount, 0, false); err != nil {
+			return nil, failOnError(err, "Failed to set the prefetch count of the queue consumer")
+		}
+	}
+
+	queueName := queueOptions.QueueName
+	if queueOptions.Exchange != "" {
+		queue, err := ch.QueueDeclare(
+			"",    // name - generated by the broker
+			false, // durable
+			true,  // auto-deleted
+			true,  // exclusive
+			false, // no-wait
+			nil,   // arguments
+		)
+		if err != nil {
+			return nil, failOnError(err, "Failed to declare the queue of the consumer")
+		}
+		if err := ch.QueueBind(queue.Name, queueOptions.RoutingKey, queueOptions.Exchange, false, nil); err != nil {
+			return nil, failOnError(err, fmt.Sprintf("Failed to bind the queue of the consumer to the exchange %s", queueOptions.Exchange))
+		}
+		queueName = queue.Name
+	}
+
+	deliveries, err := ch.Consume(
+		queueName,
+		"",    // consumer tag - generated by the broker
+		true,  // auto-ack
+		false, // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,   // arguments
+	)
+	return deliveries, failOnError(err, fmt.Sprintf("Failed to consume the queue %s", queueOptions.getQueueDescription()))
+}
+
+func (client *RabbitMQStreamClient) addQueueSubscription(subscription *QueueSubscription) {
+	client.queueMutex.Lock()
+	defer client.queueMutex.Unlock()
+	if client.queueSubscriptions == nil {
+		client.queueSubscriptions = make(map[*QueueSubscription]struct{})
+	}
+	client.queueSubscriptions[subscription] = struct{}{}
+}
+
+func (client *RabbitMQStreamClient) removeQueueSubscription(subscription *QueueSubscription) {
+	client.queueMutex.Lock()
+	defer client.queueMutex.Unlock()
+	delete(client.queueSubscriptions, subscription)
+}
+
+// closeQueueSubscriptions closes the consumers of the queues once the client is disposed.
+func (client *RabbitMQStreamClient) closeQueueSubscriptions() {
+	client.queueMutex.Lock()
+	subscriptions := client.queueSubscriptions
+	client.queueSubscriptions = nil
+	client.queueMutex.Unlock()
+
+	for subscription := range subscriptions {
+		if err := subscription.Close(); err != nil {
+			log.DefaultLogger.Debug("Failed to close the queue consumer", "error", err)
+		}
+	}
+}
+
+func (subscription *QueueSubscription) markClosed() {
+	subscription.closeOnce.Do(func() {
+		close(subscription.closed)
+	})
+}
+
+// NotifyClose is closed once the consumer is closed, by the broker or by the subscription.
+func (subscription *QueueSubscription) NotifyClose() <-chan struct{} {
+	return subscription.closed
+}
+
+// Close closes the AMQP connection of the consumer, which also deletes its exclusive queue.
+func (subscription *QueueSubscription) Close() error {
+	err := subscription.conn.Close()
+	subscription.markClosed()
+	if err == amqp.ErrClosed {
+		return nil
+	}
+	return err
+}
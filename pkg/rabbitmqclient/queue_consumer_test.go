@@ -0,0 +1,34 @@
+package rabbitmqclient
+
+import "testing"
+
+func TestValidateQueueConsumer(t *testing.T) {
+	tests := []struct {
+		name            string
+		queueOptions    *QueueConsumerOptions
+		expectError     bool
+		expectedEnabled bool
+	}{
+		{name: "no queue consumer", queueOptions: nil},
+		{name: "empty queue name", queueOptions: &QueueConsumerOptions{RoutingKey: "#"}},
+		{name: "queue name", queueOptions: &QueueConsumerOptions{QueueName: "orders", TakeMessages: true}, expectedEnabled: true},
+		{name: "queue name without taking its messages", queueOptions: &QueueConsumerOptions{QueueName: "orders"}, expectError: true},
+		{name: "exchange", queueOptions: &QueueConsumerOptions{Exchange: "orders", RoutingKey: "#", PrefetchCount: 10}, expectedEnabled: true},
+		{name: "queue name and exchange", queueOptions: &QueueConsumerOptions{QueueName: "orders", Exchange: "orders", TakeMessages: true}, expectError: true},
+		{name: "negative prefetch count", queueOptions: &QueueConsumerOptions{Exchange: "orders", PrefetchCount: -1}, expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.queueOptions.Validate()
+			if (err != nil) != test.expectError {
+				t.Fatalf("unexpected validation error: got %v, want an error: %v", err, test.expectError)
+			}
+			if test.expectError {
+				return
+			}
+			if enabled := test.queueOptions.IsEnabled(); enabled != test.expectedEnabled {
+				t.Errorf("unexpected enabled: got %v, want %v", enabled, test.expectedEnabled)
+			}
+		})
+	}
+}
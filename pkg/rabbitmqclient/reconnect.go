@@ -0,0 +1,195 @@
+package rabbitmqclient
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+const (
+	CONNECTION_STATUS_CONNECTED    = "connected"
+	CONNECTION_STATUS_RECONNECTING = "reconnecting"
+	CONNECTION_STATUS_FAILED       = "failed"
+	CONNECTION_STATUS_DISPOSED     = "disposed"
+
+	DEFAULT_RECONNECT_INITIAL_INTERVAL_MS = 500
+	DEFAULT_RECONNECT_MAX_INTERVAL_MS     = 30_000
+	DEFAULT_RECONNECT_MULTIPLIER          = 2
+	DEFAULT_RECONNECT_JITTER              = 0.2
+)
+
+var ErrClientDisposed = errors.New("the RabbitMQ client was disposed")
+var ErrNotConnected = errors.New("the RabbitMQ environment is not connected")
+
+// ReconnectOptions configures the exponential backoff between the reconnection attempts.
+// Zero values fall back to the defaults, and zero MaxAttempts/MaxElapsedSeconds never give up.
+type ReconnectOptions struct {
+	InitialIntervalMs int     `json:"initialIntervalMs"`
+	MaxIntervalMs     int     `json:"maxIntervalMs"`
+	Multiplier        float64 `json:"multiplier"`
+	// Jitter is the fraction of the interval that is randomly added or removed, between 0 and 1
+	Jitter            float64 `json:"jitter"`
+	MaxAttempts       int     `json:"maxAttempts"`
+	MaxElapsedSeconds int     `json:"maxElapsedSeconds"`
+}
+
+// ConnectionState tells if the client is connected, or how its reconnection is going.
+type ConnectionState struct {
+	Status    string
+	Attempt   int
+	LastError error
+	Since     time.Time
+}
+
+// reconnection is shared by every caller of Reconnect while the connection is being recreated.
+type reconnection struct {
+	done chan struct{}
+	err  error
+}
+
+func NewReconnectOptions() *ReconnectOptions {
+	return &ReconnectOptions{}
+}
+
+func (reconnectOptions *ReconnectOptions) Validate() error {
+	if reconnectOptions.InitialIntervalMs < 0 || reconnectOptions.MaxIntervalMs < 0 {
+		return fmt.Errorf("invalid reconnect interval: the intervals must not be negative")
+	}
+	if reconnectOptions.Multiplier != 0 && reconnectOptions.Multiplier < 1 {
+		return fmt.Errorf("invalid reconnect multiplier %v: the multiplier must be at least 1", reconnectOptions.Multiplier)
+	}
+	if reconnectOptions.Jitter < 0 || reconnectOptions.Jitter > 1 {
+		return fmt.Errorf("invalid reconnect jitter %v: the jitter must be between 0 and 1", reconnectOptions.Jitter)
+	}
+	if reconnectOptions.MaxAttempts < 0 || reconnectOptions.MaxElapsedSeconds < 0 {
+		return fmt.Errorf("invalid reconnect limit: the max attempts and max elapsed seconds must not be negative")
+	}
+	return nil
+}
+
+func (reconnectOptions *ReconnectOptions) getInitialInterval() time.Duration {
+	if reconnectOptions.InitialIntervalMs <= 0 {
+		return DEFAULT_RECONNECT_INITIAL_INTERVAL_MS * time.Millisecond
+	}
+	return time.Duration(reconnectOptions.InitialIntervalMs) * time.Millisecond
+}
+
+func (reconnectOptions *ReconnectOptions) getMaxInterval() time.Duration {
+	if reconnectOptions.MaxIntervalMs <= 0 {
+		return DEFAULT_RECONNECT_MAX_INTERVAL_MS * time.Millisecond
+	}
+	return time.Duration(reconnectOptions.MaxIntervalMs) * time.Millisecond
+}
+
+func (reconnectOptions *ReconnectOptions) getMultiplier() float64 {
+	if reconnectOptions.Multiplier < 1 {
+		return DEFAULT_RECONNECT_MULTIPLIER
+	}
+	return reconnectOptions.Multiplier
+}
+
+func (reconnectOptions *ReconnectOptions) getJitter() float64 {
+	if reconnectOptions.Jitter <= 0 {
+		return DEFAULT_RECONNECT_JITTER
+	}
+	return reconnectOptions.Jitter
+}
+
+// getDelay returns the backoff before the given attempt, starting from the first attempt.
+func (reconnectOptions *ReconnectOptions) getDelay(attempt int) time.Duration {
+	delay := float64(reconnectOptions.getInitialInterval()) * math.Pow(reconnectOptions.getMultiplier(), float64(attempt-1))
+	delay = math.Min(delay, float64(reconnectOptions.getMaxInterval()))
+	jitter := reconnectOptions.getJitter()
+	delay = delay * (1 - jitter + 2*jitter*rand.Float64())
+	return time.Duration(delay)
+}
+
+// shouldGiveUp tells if the attempt exceeds the max attempts or the deadline of the reconnection.
+func (reconnectOptions *ReconnectOptions) shouldGiveUp(attempt int, startedAt time.Time, delay time.Duration) bool {
+	if reconnectOptions.MaxAttempts > 0 && attempt > reconnectOptions.MaxAttempts {
+		return true
+	}
+	if reconnectOptions.MaxElapsedSeconds > 0 {
+		deadline := startedAt.Add(time.Duration(reconnectOptions.MaxElapsedSeconds) * time.Second)
+		return time.Now().Add(delay).After(deadline)
+	}
+	return false
+}
+
+// reconnect recreates the environment with an exponential backoff, until it succeeds, the
+// reconnect limits are reached or the client is disposed. Every attempt only closes the
+// environment and declares the RabbitMQ objects again, nothing is disposed.
+func (client *RabbitMQStreamClient) reconnect(current *reconnection) {
+	reconnectOptions := client.getReconnectOptions()
+	startedAt := time.Now()
+	var lastErr error
+
+	for attempt := 1; ; attempt += 1 {
+		delay := reconnectOptions.getDelay(attempt)
+		if reconnectOptions.shouldGiveUp(attempt, startedAt, delay) {
+			current.err = fmt.Errorf("failed to reconnect to the RabbitMQ after %d attempts: %w", attempt-1, lastErr)
+			client.setConnectionState(CONNECTION_STATUS_FAILED, attempt-1, lastErr)
+			break
+		}
+		client.setConnectionState(CONNECTION_STATUS_RECONNECTING, attempt, lastErr)
+
+		select {
+		case <-client.disposed:
+			current.err = ErrClientDisposed
+		case <-time.After(delay):
+		}
+		if current.err != nil {
+			break
+		}
+
+		log.DefaultLogger.Debug("Trying to reconnect to RabbitMQ", "RabbitMQ Stream", client.ToString(), "attempt", attempt)
+		if lastErr = client.replaceEnvironment(); lastErr == nil {
+			log.DefaultLogger.Info("Reconnected to RabbitMQ", "RabbitMQ Stream", client.ToString(), "attempts", attempt)
+			break
+		}
+		log.DefaultLogger.Warn("Failed to reconnect to RabbitMQ", "RabbitMQ Stream", client.ToString(), "attempt", attempt, "error", lastErr)
+	}
+
+	client.reconnectMutex.Lock()
+	client.reconnection = nil
+	client.reconnectMutex.Unlock()
+	close(current.done)
+}
+
+// replaceEnvironment closes the lost environment and connects again, without letting a concurrent Connect in between.
+func (client *RabbitMQStreamClient) replaceEnvironment() error {
+	client.connectMutex.Lock()
+	defer client.connectMutex.Unlock()
+	if err := client.closeEnvironment(); err != nil {
+		log.DefaultLogger.Debug("Failed to close the lost RabbitMQ environment", "error", err)
+	}
+	return client.connect()
+}
+
+func (client *RabbitMQStreamClient) getReconnectOptions() *ReconnectOptions {
+	if client.RabbitMQOptions.ReconnectOptions == nil {
+		return NewReconnectOptions()
+	}
+	return client.RabbitMQOptions.ReconnectOptions
+}
+
+func (client *RabbitMQStreamClient) setConnectionState(status string, attempt int, lastErr error) {
+	client.stateMutex.Lock()
+	defer client.stateMutex.Unlock()
+	if client.connectionState.Status != status {
+		client.connectionState.Since = time.Now()
+	}
+	client.connectionState.Status = status
+	client.connectionState.Attempt = attempt
+	client.connectionState.LastError = lastErr
+}
+
+func (client *RabbitMQStreamClient) GetConnectionState() ConnectionState {
+	client.stateMutex.Lock()
+	defer client.stateMutex.Unlock()
+	return client.connectionState
+}
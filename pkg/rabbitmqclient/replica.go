@@ -0,0 +1,104 @@
+package rabbitmqclient
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	stream "github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+// subscribeToReplica subscribes through an environment that is pinned to a replica of the stream,
+// since the environment of the datasource picks randomly between the leader and the replicas. The
+// replicas are tried in a random order, then the leader, and finally the environment of the datasource.
+func (client *RabbitMQStreamClient) subscribeToReplica(streamOptions *StreamOptions, messagesHandler stream.MessagesHandler) (*ConsumerSubscription, error) {
+	datasourceEnv, err := client.getEnv()
+	if err != nil {
+		return nil, err
+	}
+	brokers, err := client.getConsumerBrokers(datasourceEnv, streamOptions.StreamName)
+	if err != nil {
+		log.DefaultLogger.Warn("Failed to get the replicas of the stream, consuming from any node", "stream", streamOptions.StreamName, "error", err)
+		return client.Consumers.Subscribe(datasourceEnv, streamOptions, messagesHandler)
+	}
+
+	for _, broker := range brokers {
+		env, err := client.getNodeEnvironment(broker)
+		if err == nil {
+			var subscription *ConsumerSubscription
+			if subscription, err = client.Consumers.Subscribe(env, streamOptions, messagesHandler); err == nil {
+				return subscription, nil
+			}
+		}
+		// the environment of the node is kept, since the other consumers of the node may still use it
+		log.DefaultLogger.Warn("Failed to consume from the node, trying the next one", "stream", streamOptions.StreamName, "host", broker.Host, "port", broker.Port, "error", err)
+	}
+	return client.Consumers.Subscribe(datasourceEnv, streamOptions, messagesHandler)
+}
+
+// getConsumerBrokers returns the replicas of the stream in a random order, followed by the leader.
+func (client *RabbitMQStreamClient) getConsumerBrokers(env *stream.Environment, streamName string) ([]*stream.Broker, error) {
+	streamMetadata, err := env.StreamMetaData(streamName)
+	if err != nil {
+		return nil, err
+	}
+	brokers := make([]*stream.Broker, 0, len(streamMetadata.Replicas)+1)
+	for _, replicaIndex := range rand.Perm(len(streamMetadata.Replicas)) {
+		if replica := streamMetadata.Replicas[replicaIndex]; replica != nil {
+			brokers = append(brokers, replica)
+		}
+	}
+	return append(brokers, streamMetadata.Leader), nil
+}
+
+// getNodeEnvironment returns the environment of the node, which connects every consumer
+// to the node itself, so the advertised host of the node must be reachable. The environment
+// is shared by the consumers of the node and stays open until closeNodeEnvironments.
+func (client *RabbitMQStreamClient) getNodeEnvironment(broker *stream.Broker) (*stream.Environment, error) {
+	client.nodeEnvsMutex.Lock()
+	defer client.nodeEnvsMutex.Unlock()
+
+	nodeKey := net.JoinHostPort(broker.Host, broker.Port)
+	if env, exists := client.nodeEnvs[nodeKey]; exists && !env.IsClosed() {
+		return env, nil
+	}
+
+	port, err := strconv.Atoi(broker.Port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q of the node %s", broker.Port, broker.Host)
+	}
+	newEnvOptions, err := client.getEnvOptionsFactory()
+	if err != nil {
+		return nil, err
+	}
+	envOptions := newEnvOptions()
+	nodeEndpoint := brokerEndpoint{host: broker.Host, streamPort: port}
+	setBrokers(envOptions, nodeEndpoint)
+	envOptions.IsTLS(client.RabbitMQOptions.IsTLS)
+	envOptions.SetAddressResolver(stream.AddressResolver{Host: broker.Host, Port: port})
+
+	env, err := stream.NewEnvironment(envOptions)
+	if err != nil {
+		return nil, err
+	}
+	if client.nodeEnvs == nil {
+		client.nodeEnvs = make(map[string]*stream.Environment)
+	}
+	client.nodeEnvs[nodeKey] = env
+	return env, nil
+}
+
+// closeNodeEnvironments closes the environments of the nodes, after their consumers were closed.
+func (client *RabbitMQStreamClient) closeNodeEnvironments() {
+	client.nodeEnvsMutex.Lock()
+	defer client.nodeEnvsMutex.Unlock()
+
+	for nodeKey, env := range client.nodeEnvs {
+		if err := env.Close(); err != nil {
+			log.DefaultLogger.Debug("Failed to close the environment of the node", "node", nodeKey, "error", err)
+		}
+	}
+	client.nodeEnvs = nil
+}
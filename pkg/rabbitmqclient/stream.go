@@ -1,19 +1,40 @@
 package rabbitmqclient
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
 	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
 )
 
-var ErrConsumerWasAlreadyCreated = errors.New("consumer was already created")
+// readIdleTimeout stops a bounded read when the stream stopped delivering messages,
+// for example when there are no messages after the requested offset.
+// readTailIdleTimeout is used once the read reached the last chunk of the stream, since the
+// stats only tell where the last chunk starts, and its messages are delivered together.
+const readIdleTimeout time.Duration = 2000 * time.Millisecond
+const readTailIdleTimeout time.Duration = 200 * time.Millisecond
+
+// offsetProbeTimeout stops looking for the offset of a timestamp when no chunk was delivered, the
+// broker delivers the first chunk right away when the stream has messages after the timestamp.
+const offsetProbeTimeout time.Duration = 500 * time.Millisecond
+
+// ConsumerUpdateHandler is told when a single active consumer is promoted as active or becomes a standby.
+type ConsumerUpdateHandler func(isActive bool)
+
+// ReadHandler handles a message of a bounded read, it returns false to stop reading.
+type ReadHandler func(consumerContext stream.ConsumerContext, message *amqp.Message) bool
 
 type Stream interface {
-	CreateStream(*stream.Environment) error
+	CreateStream(StreamTopology) error
 	DisposeStream(*stream.Environment) error
-	Consume(*stream.Environment, stream.MessagesHandler) (*stream.Consumer, error)
+	Consume(*stream.Environment, stream.MessagesHandler, ConsumerUpdateHandler) (*stream.Consumer, error)
+	Read(context.Context, *stream.Environment, stream.OffsetSpecification, ReadHandler) error
+	OffsetAt(context.Context, *stream.Environment, time.Time) (int64, error)
 }
 
 type StreamOptions struct {
@@ -23,12 +44,23 @@ type StreamOptions struct {
 	MaxSegmentSizeBytes int64         `json:"maxSegmentSizeBytes"`
 	ConsumerName        string        `json:"consumerName"`
 	OffsetFromStart     bool          `json:"offsetFromStart"`
-	Crc                 bool          `json:"crc"`
-	ShouldDisposeStream bool          `json:"ShouldDisposeStream"`
-	Consumer            *stream.Consumer
+	// OffsetOptions takes precedence over OffsetFromStart when its type is set
+	OffsetOptions        *OffsetOptions        `json:"offsetOptions,omitempty"`
+	OffsetStorage        *OffsetStorageOptions `json:"offsetStorage,omitempty"`
+	Crc                  bool                  `json:"crc"`
+	ShouldDisposeStream  bool                  `json:"ShouldDisposeStream"`
+	PreferReplica        bool                  `json:"preferReplica"`
+	SuperStream          bool                  `json:"superStream"`
+	PartitionCount       int                   `json:"partitionCount"`
+	ChosenPartitions     []string              `json:"partitions"`
+	SingleActiveConsumer bool                  `json:"singleActiveConsumer"`
+	Filter               *StreamFilterOptions  `json:"filter,omitempty"`
 }
 
-func (streamOptions *StreamOptions) CreateStream(env *stream.Environment) error {
+func (streamOptions *StreamOptions) CreateStream(env StreamTopology) error {
+	if streamOptions.SuperStream {
+		return streamOptions.createSuperStream(env)
+	}
 	err := env.DeclareStream(streamOptions.StreamName,
 		stream.NewStreamOptions().
 			SetMaxAge(streamOptions.MaxAge*time.Nanosecond).
@@ -39,11 +71,9 @@ func (streamOptions *StreamOptions) CreateStream(env *stream.Environment) error
 }
 
 func (streamOptions *StreamOptions) DisposeStream(env *stream.Environment) error {
-	err := streamOptions.closeConsumer()
-	if err != nil {
-		return err
+	if streamOptions.ShouldDisposeStream && streamOptions.SuperStream {
+		return env.DeleteSuperStream(streamOptions.StreamName)
 	}
-
 	if streamOptions.ShouldDisposeStream {
 		return env.DeleteStream(streamOptions.StreamName)
 	}
@@ -51,38 +81,192 @@ func (streamOptions *StreamOptions) DisposeStream(env *stream.Environment) error
 	return nil
 }
 
-func (streamOptions *StreamOptions) Consume(env *stream.Environment, messagesHandler stream.MessagesHandler) (*stream.Consumer, error) {
-	if streamOptions.Consumer != nil {
-		return nil, failOnError(ErrConsumerWasAlreadyCreated,
-			fmt.Sprintf("StreamName: %s; ConsumerName:%s",
-				streamOptions.ConsumerName,
-				streamOptions.getConsumerName(),
-			),
-		)
+func (streamOptions *StreamOptions) Consume(env *stream.Environment, messagesHandler stream.MessagesHandler, consumerUpdateHandler ConsumerUpdateHandler) (*stream.Consumer, error) {
+	offsetSettings, err := streamOptions.getResumeOffsetSettings(env)
+	if err != nil {
+		return nil, err
+	}
+	consumerOptions := stream.NewConsumerOptions().
+		SetConsumerName(streamOptions.getConsumerName()). // Set a consumer name
+		SetOffset(offsetSettings).                        // Start consuming from the configured offset
+		SetCRCCheck(streamOptions.Crc)                    // Disabled CRC control increase the performances
+	if streamOptions.OffsetStorage.IsEnabled() && !streamOptions.OffsetStorage.IsPeriodic() {
+		consumerOptions.SetAutoCommit(streamOptions.OffsetStorage.toAutoCommitStrategy())
+	}
+	if streamOptions.Filter.IsEnabled() {
+		consumerOptions.SetFilter(streamOptions.Filter.toConsumerFilter())
+	}
+	if streamOptions.SingleActiveConsumer {
+		consumerOptions.SetSingleActiveConsumer(stream.NewSingleActiveConsumer(streamOptions.newConsumerUpdate(env, offsetSettings, consumerUpdateHandler)))
+	}
+
+	consumer, err := env.NewConsumer(streamOptions.StreamName, messagesHandler, consumerOptions)
+	if err != nil {
+		return nil, failOnError(err, fmt.Sprintf("Failed to create the consumer: %s", streamOptions.getConsumerName()))
+	}
+	return consumer, nil
+}
+
+// Read consumes the stream from the given offset with a short-lived consumer, until the last
+// chunk that was stored when the read started, the handler asks to stop or the context is done.
+func (streamOptions *StreamOptions) Read(ctx context.Context, env *stream.Environment, offset stream.OffsetSpecification, readHandler ReadHandler) error {
+	return streamOptions.read(ctx, env, offset, readIdleTimeout, readHandler)
+}
+
+// OffsetAt returns the first offset of the chunk that was stored at the given time, or -1 when nothing was
+// stored since then. The timestamps of a stream belong to its chunks, so the chunk can start a bit before the time.
+func (streamOptions *StreamOptions) OffsetAt(ctx context.Context, env *stream.Environment, timestamp time.Time) (int64, error) {
+	offset := int64(-1)
+	err := streamOptions.read(ctx, env, stream.OffsetSpecification{}.Timestamp(timestamp.UnixMilli()), offsetProbeTimeout, func(consumerContext stream.ConsumerContext, _ *amqp.Message) bool {
+		offset = consumerContext.Consumer.GetOffset()
+		return false
+	})
+	return offset, err
+}
+
+func (streamOptions *StreamOptions) read(ctx context.Context, env *stream.Environment, offset stream.OffsetSpecification, idleTimeout time.Duration, readHandler ReadHandler) error {
+	stats, err := env.StreamStats(streamOptions.StreamName)
+	if err != nil {
+		return failOnError(err, fmt.Sprintf("Failed to get the stats of the stream: %s", streamOptions.StreamName))
 	}
+	lastChunkOffset, err := stats.LastOffset()
+	if err != nil {
+		// the stream is empty
+		return nil
+	}
+
+	var mutex sync.Mutex
+	isStopped := false
+	stopped := make(chan struct{})
+	received := make(chan bool, 1)
+	stop := func() {
+		if !isStopped {
+			isStopped = true
+			close(stopped)
+		}
+	}
+
+	consumerOptions := stream.NewConsumerOptions().
+		SetOffset(offset).
+		SetCRCCheck(streamOptions.Crc)
+	if streamOptions.Filter.IsEnabled() {
+		consumerOptions.SetFilter(streamOptions.Filter.toConsumerFilter())
+	}
+
 	consumer, err := env.NewConsumer(
 		streamOptions.StreamName,
-		messagesHandler,
-		stream.NewConsumerOptions().
-			SetConsumerName(streamOptions.getConsumerName()). // Set a consumer name
-			SetOffset(streamOptions.getOffsetSettings()).     // Start consuming from the beginning
-			SetCRCCheck(streamOptions.Crc),                   // Disabled CRC control increase the performances
+		func(consumerContext stream.ConsumerContext, message *amqp.Message) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if isStopped {
+				return
+			}
+			if !readHandler(consumerContext, message) {
+				stop()
+				return
+			}
+			select {
+			case received <- consumerContext.Consumer.GetOffset() >= lastChunkOffset:
+			default:
+			}
+		},
+		consumerOptions,
 	)
 	if err != nil {
-		return nil, failOnError(err, fmt.Sprintf("Failed to create the consumer: %s", streamOptions.ConsumerName))
+		return failOnError(err, fmt.Sprintf("Failed to create a reader of the stream: %s", streamOptions.StreamName))
+	}
+
+	idleTimer := time.NewTimer(idleTimeout)
+	defer idleTimer.Stop()
+	for waiting := true; waiting; {
+		select {
+		case <-stopped:
+			waiting = false
+		case <-ctx.Done():
+			err = ctx.Err()
+			waiting = false
+		case <-idleTimer.C:
+			waiting = false
+		case isLastChunk := <-received:
+			if isLastChunk {
+				idleTimer.Reset(readTailIdleTimeout)
+			} else {
+				idleTimer.Reset(idleTimeout)
+			}
+		}
+	}
+
+	mutex.Lock()
+	stop()
+	mutex.Unlock()
+
+	if closeErr := consumer.Close(); closeErr != nil && !errors.Is(closeErr, stream.AlreadyClosed) {
+		return closeErr
+	}
+	return err
+}
+
+// GetOffsetOptions returns the offset options, or the first/last offset of OffsetFromStart when they are not set.
+func (streamOptions *StreamOptions) GetOffsetOptions() *OffsetOptions {
+	if streamOptions.OffsetOptions == nil || streamOptions.OffsetOptions.Type == "" {
+		return NewOffsetOptions(streamOptions.OffsetFromStart)
+	}
+	return streamOptions.OffsetOptions
+}
+
+func (streamOptions *StreamOptions) getOffsetSettings() (stream.OffsetSpecification, error) {
+	offsetSettings, err := streamOptions.GetOffsetOptions().ToOffsetSpecification()
+	if err != nil {
+		return offsetSettings, fmt.Errorf("invalid offset of the stream %s: %w", streamOptions.StreamName, err)
+	}
+	return offsetSettings, nil
+}
+
+// getResumeOffsetSettings resumes after the offset stored by the consumer name when the offset
+// storage is enabled, and falls back to the configured offset when nothing was stored yet.
+func (streamOptions *StreamOptions) getResumeOffsetSettings(env *stream.Environment) (stream.OffsetSpecification, error) {
+	if !streamOptions.OffsetStorage.IsEnabled() {
+		return streamOptions.getOffsetSettings()
 	}
-	streamOptions.Consumer = consumer
-	return streamOptions.Consumer, nil
+	storedOffset, err := env.QueryOffset(streamOptions.getConsumerName(), streamOptions.StreamName)
+	return streamOptions.resumeAfterStoredOffset(storedOffset, err)
 }
 
-func (streamOptions *StreamOptions) getOffsetSettings() stream.OffsetSpecification {
-	offsetSettings := stream.OffsetSpecification{}
-	if streamOptions.OffsetFromStart {
-		offsetSettings = offsetSettings.First()
-	} else {
-		offsetSettings = offsetSettings.Last()
+// resumeAfterStoredOffset returns the offset after the stored offset, or the configured offset when the stored offset couldn't be queried.
+func (streamOptions *StreamOptions) resumeAfterStoredOffset(storedOffset int64, err error) (stream.OffsetSpecification, error) {
+	// an absolute offset after the stored one was already delivered, see ResumeAfter
+	if err == nil && streamOptions.GetOffsetOptions().Type == OFFSET_OFFSET && streamOptions.GetOffsetOptions().Offset > storedOffset+1 {
+		return streamOptions.getOffsetSettings()
+	}
+	if err == nil {
+		log.DefaultLogger.Debug("Resuming the consumer after its stored offset", "consumer", streamOptions.getConsumerName(), "offset", storedOffset)
+		return stream.OffsetSpecification{}.Offset(storedOffset + 1), nil
+	}
+	if !errors.Is(err, stream.OffsetNotFoundError) {
+		log.DefaultLogger.Warn("Failed to query the stored offset, using the configured offset", "consumer", streamOptions.getConsumerName(), "error", err)
+	}
+	return streamOptions.getOffsetSettings()
+}
+
+// newConsumerUpdate returns the listener of a single active consumer. Once it is promoted as active, the
+// consumer resumes after the offset that was stored by the previous active consumer of the same name.
+func (streamOptions *StreamOptions) newConsumerUpdate(env *stream.Environment, initialOffsetSettings stream.OffsetSpecification, consumerUpdateHandler ConsumerUpdateHandler) stream.ConsumerUpdate {
+	return func(streamName string, isActive bool) stream.OffsetSpecification {
+		if consumerUpdateHandler != nil {
+			consumerUpdateHandler(isActive)
+		}
+		if !isActive {
+			log.DefaultLogger.Info("Consumer became a standby of the single active consumer", "consumer", streamOptions.getConsumerName(), "stream", streamName)
+			return initialOffsetSettings
+		}
+		log.DefaultLogger.Info("Consumer was promoted as the single active consumer", "consumer", streamOptions.getConsumerName(), "stream", streamName)
+		offsetSettings, err := streamOptions.getResumeOffsetSettings(env)
+		if err != nil {
+			log.DefaultLogger.Warn("Failed to get the offset of the promoted consumer, using its initial offset", "consumer", streamOptions.getConsumerName(), "error", err)
+			return initialOffsetSettings
+		}
+		return offsetSettings
 	}
-	return offsetSettings
 }
 
 func (streamOptions *StreamOptions) getConsumerName() string {
@@ -92,13 +276,35 @@ func (streamOptions *StreamOptions) getConsumerName() string {
 	return streamOptions.ConsumerName
 }
 
-func (streamOptions *StreamOptions) closeConsumer() error {
-	if streamOptions.Consumer == nil {
-		return nil
-	} else if err := streamOptions.Consumer.Close(); err != nil {
-		return failOnError(err, fmt.Sprintf("Failed to close the consumer: %s", streamOptions.ConsumerName))
-	} else {
-		streamOptions.Consumer = nil
-		return nil
+// ResumeAfter returns a copy of the stream options that starts consuming right after the
+// given offset, so a consumer that is recreated doesn't lose or repeat messages.
+func (streamOptions *StreamOptions) ResumeAfter(offset int64) *StreamOptions {
+	clone := streamOptions.Clone()
+	clone.OffsetOptions = &OffsetOptions{Type: OFFSET_OFFSET, Offset: offset + 1}
+	return clone
+}
+
+// FirstOffset returns the first offset that is still stored in the stream, older messages were removed by the retention.
+func (streamOptions *StreamOptions) FirstOffset(env *stream.Environment) (int64, error) {
+	stats, err := env.StreamStats(streamOptions.StreamName)
+	if err != nil {
+		return 0, failOnError(err, fmt.Sprintf("Failed to get the stats of the stream: %s", streamOptions.StreamName))
+	}
+	return stats.FirstOffset()
+}
+
+// Clone returns a copy of the stream options, so a query can override the
+// consumer settings without touching the datasource stream.
+func (streamOptions *StreamOptions) Clone() *StreamOptions {
+	clone := *streamOptions
+	return &clone
+}
+
+func (streamOptions *StreamOptions) consumerKey() ConsumerKey {
+	return ConsumerKey{
+		StreamName:   streamOptions.StreamName,
+		ConsumerName: streamOptions.getConsumerName(),
+		Offset:       streamOptions.GetOffsetOptions().String(),
+		Filter:       streamOptions.Filter.String(),
 	}
 }
@@ -0,0 +1,86 @@
+package rabbitmqclient
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+const DEFAULT_SUPER_STREAM_PARTITION_COUNT = 3
+
+func (streamOptions *StreamOptions) getPartitionCount() int {
+	if streamOptions.PartitionCount <= 0 {
+		return DEFAULT_SUPER_STREAM_PARTITION_COUNT
+	}
+	return streamOptions.PartitionCount
+}
+
+// createSuperStream declares the super stream with its partitions, every partition has the retention of the stream options.
+func (streamOptions *StreamOptions) createSuperStream(env StreamTopology) error {
+	return env.DeclareSuperStream(streamOptions.StreamName,
+		stream.NewPartitionsOptions(streamOptions.getPartitionCount()).
+			SetMaxAge(streamOptions.MaxAge*time.Nanosecond).
+			SetMaxLengthBytes(stream.ByteCapacity{}.B(streamOptions.MaxLengthBytes)).
+			SetMaxSegmentSizeBytes(stream.ByteCapacity{}.B(streamOptions.MaxSegmentSizeBytes)))
+}
+
+// Partitions returns the partitions of the super stream that should be consumed,
+// which are all of its partitions when no partition was chosen.
+func (streamOptions *StreamOptions) Partitions(env *stream.Environment) ([]string, error) {
+	if !streamOptions.SuperStream {
+		return []string{streamOptions.StreamName}, nil
+	}
+	partitions, err := env.QueryPartitions(streamOptions.StreamName)
+	if err != nil {
+		return nil, failOnError(err, fmt.Sprintf("Failed to query the partitions of the super stream: %s", streamOptions.StreamName))
+	}
+	return streamOptions.choosePartitions(partitions)
+}
+
+// choosePartitions checks that the chosen partitions are partitions of the super stream, and that none is chosen twice,
+// since its messages would be consumed twice.
+func (streamOptions *StreamOptions) choosePartitions(partitions []string) ([]string, error) {
+	if len(streamOptions.ChosenPartitions) == 0 {
+		return partitions, nil
+	}
+
+	existingPartitions := make(map[string]bool, len(partitions))
+	for _, partition := range partitions {
+		existingPartitions[partition] = true
+	}
+	chosenPartitions := make(map[string]bool, len(streamOptions.ChosenPartitions))
+	for _, partition := range streamOptions.ChosenPartitions {
+		if !existingPartitions[partition] {
+			return nil, fmt.Errorf("the super stream %s has no partition %s", streamOptions.StreamName, partition)
+		}
+		if chosenPartitions[partition] {
+			return nil, fmt.Errorf("the partition %s of the super stream %s is chosen more than once", partition, streamOptions.StreamName)
+		}
+		chosenPartitions[partition] = true
+	}
+	return streamOptions.ChosenPartitions, nil
+}
+
+// PartitionOptions returns a copy of the stream options that consumes a single partition of the super stream.
+func (streamOptions *StreamOptions) PartitionOptions(partition string) *StreamOptions {
+	clone := streamOptions.Clone()
+	clone.StreamName = partition
+	clone.SuperStream = false
+	clone.ChosenPartitions = nil
+	return clone
+}
+
+// exists checks that the stream exists, or that the super stream still has partitions. The broker
+// answers the query of the partitions of a missing super stream with a stream does not exist error.
+func (streamOptions *StreamOptions) exists(env StreamTopology) (bool, error) {
+	if !streamOptions.SuperStream {
+		return env.StreamExists(streamOptions.StreamName)
+	}
+	partitions, err := env.QueryPartitions(streamOptions.StreamName)
+	if errors.Is(err, stream.StreamDoesNotExist) {
+		return false, nil
+	}
+	return len(partitions) > 0, err
+}
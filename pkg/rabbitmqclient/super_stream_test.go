@@ -0,0 +1,77 @@
+package rabbitmqclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChoosePartitions(t *testing.T) {
+	partitions := []string{"invoices-0", "invoices-1", "invoices-2"}
+	tests := []struct {
+		name               string
+		chosenPartitions   []string
+		expectedPartitions []string
+		expectError        bool
+	}{
+		{
+			name:               "no chosen partition",
+			chosenPartitions:   nil,
+			expectedPartitions: partitions,
+		},
+		{
+			name:               "empty selection",
+			chosenPartitions:   []string{},
+			expectedPartitions: partitions,
+		},
+		{
+			name:               "chosen partitions",
+			chosenPartitions:   []string{"invoices-2", "invoices-0"},
+			expectedPartitions: []string{"invoices-2", "invoices-0"},
+		},
+		{
+			name:             "unknown partition",
+			chosenPartitions: []string{"invoices-0", "invoices-3"},
+			expectError:      true,
+		},
+		{
+			name:             "duplicate partitions",
+			chosenPartitions: []string{"invoices-1", "invoices-1"},
+			expectError:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streamOptions := &StreamOptions{StreamName: "invoices", SuperStream: true, ChosenPartitions: test.chosenPartitions}
+			actualPartitions, err := streamOptions.choosePartitions(partitions)
+			if (err != nil) != test.expectError {
+				t.Fatalf("unexpected error: got %v, want an error: %v", err, test.expectError)
+			}
+			if !reflect.DeepEqual(actualPartitions, test.expectedPartitions) {
+				t.Errorf("unexpected partitions: got %v, want %v", actualPartitions, test.expectedPartitions)
+			}
+		})
+	}
+}
+
+func TestPartitionsOfStream(t *testing.T) {
+	// a stream is its only partition, so no environment is needed
+	actualPartitions, err := (&StreamOptions{StreamName: "invoices"}).Partitions(nil)
+	if err != nil {
+		t.Fatalf("Partitions returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(actualPartitions, []string{"invoices"}) {
+		t.Errorf("unexpected partitions: got %v, want [invoices]", actualPartitions)
+	}
+}
+
+func TestPartitionOptions(t *testing.T) {
+	streamOptions := &StreamOptions{StreamName: "invoices", SuperStream: true, ChosenPartitions: []string{"invoices-1"}, ConsumerName: "grafana"}
+	partitionOptions := streamOptions.PartitionOptions("invoices-1")
+	expectedOptions := &StreamOptions{StreamName: "invoices-1", ConsumerName: "grafana"}
+	if !reflect.DeepEqual(partitionOptions, expectedOptions) {
+		t.Errorf("unexpected partition options: got %+v, want %+v", partitionOptions, expectedOptions)
+	}
+	if !streamOptions.SuperStream || streamOptions.StreamName != "invoices" {
+		t.Errorf("the options of the super stream were changed: %+v", streamOptions)
+	}
+}
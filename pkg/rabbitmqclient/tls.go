@@ -0,0 +1,37 @@
+package rabbitmqclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+// getTLSConfig builds the TLS configuration shared by the stream environment and the AMQP connection.
+// The certificates and the key are PEM encoded, and the system CAs are used when no CA certificate is set.
+func (options *RabbitMQStreamOptions) getTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         options.TLSServerName,
+		InsecureSkipVerify: options.TLSSkipVerify,
+	}
+
+	if options.TLSCACert != "" {
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM([]byte(options.TLSCACert)) {
+			return nil, fmt.Errorf("failed to parse the TLS CA certificate: no PEM certificate was found")
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	if options.TLSClientCert != "" || options.TLSClientKey != "" {
+		if options.TLSClientCert == "" || options.TLSClientKey == "" {
+			return nil, fmt.Errorf("both the TLS client certificate and the TLS client key are required for mutual TLS")
+		}
+		clientCert, err := tls.X509KeyPair([]byte(options.TLSClientCert), []byte(options.TLSClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the TLS client certificate and key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return tlsConfig, nil
+}
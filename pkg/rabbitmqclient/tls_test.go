@@ -0,0 +1,123 @@
+package rabbitmqclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newPEMCertificate generates a self-signed certificate and its key, both PEM encoded.
+func newPEMCertificate(t *testing.T, commonName string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate a key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create a certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal the key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestGetTLSConfig(t *testing.T) {
+	caCert, _ := newPEMCertificate(t, "rabbitmq-ca")
+	clientCert, clientKey := newPEMCertificate(t, "grafana")
+	_, otherKey := newPEMCertificate(t, "other")
+
+	tests := []struct {
+		name                 string
+		options              *RabbitMQStreamOptions
+		expectError          bool
+		expectedCA           bool
+		expectedCertificates int
+	}{
+		{
+			name:    "system CAs",
+			options: &RabbitMQStreamOptions{},
+		},
+		{
+			name:       "CA certificate",
+			options:    &RabbitMQStreamOptions{TLSCACert: caCert},
+			expectedCA: true,
+		},
+		{
+			name:        "invalid CA certificate",
+			options:     &RabbitMQStreamOptions{TLSCACert: "-----BEGIN CERTIFICATE-----\nnot a certificate\n-----END CERTIFICATE-----\n"},
+			expectError: true,
+		},
+		{
+			name:                 "client certificate",
+			options:              &RabbitMQStreamOptions{TLSCACert: caCert, TLSClientCert: clientCert, TLSClientKey: clientKey},
+			expectedCA:           true,
+			expectedCertificates: 1,
+		},
+		{
+			name:        "client certificate without its key",
+			options:     &RabbitMQStreamOptions{TLSClientCert: clientCert},
+			expectError: true,
+		},
+		{
+			name:        "client key without its certificate",
+			options:     &RabbitMQStreamOptions{TLSClientKey: clientKey},
+			expectError: true,
+		},
+		{
+			name:        "client certificate with another key",
+			options:     &RabbitMQStreamOptions{TLSClientCert: clientCert, TLSClientKey: otherKey},
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tlsConfig, err := test.options.getTLSConfig()
+			if (err != nil) != test.expectError {
+				t.Fatalf("unexpected error: got %v, want an error: %v", err, test.expectError)
+			}
+			if test.expectError {
+				return
+			}
+			if (tlsConfig.RootCAs != nil) != test.expectedCA {
+				t.Errorf("unexpected CA: got %v, want a CA: %v", tlsConfig.RootCAs, test.expectedCA)
+			}
+			if len(tlsConfig.Certificates) != test.expectedCertificates {
+				t.Errorf("unexpected client certificates: got %d, want %d", len(tlsConfig.Certificates), test.expectedCertificates)
+			}
+		})
+	}
+}
+
+func TestGetTLSConfigVerification(t *testing.T) {
+	options := &RabbitMQStreamOptions{TLSServerName: "rabbitmq.internal", TLSSkipVerify: true}
+	tlsConfig, err := options.getTLSConfig()
+	if err != nil {
+		t.Fatalf("getTLSConfig returned an error: %v", err)
+	}
+	if tlsConfig.ServerName != "rabbitmq.internal" {
+		t.Errorf("unexpected server name: got %s, want rabbitmq.internal", tlsConfig.ServerName)
+	}
+	if !tlsConfig.InsecureSkipVerify {
+		t.Errorf("the verification of the server certificate was not skipped")
+	}
+}
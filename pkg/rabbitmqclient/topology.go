@@ -0,0 +1,246 @@
+package rabbitmqclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+const (
+	TOPOLOGY_KIND_EXCHANGE     = "exchange"
+	TOPOLOGY_KIND_STREAM       = "stream"
+	TOPOLOGY_KIND_SUPER_STREAM = "super stream"
+	TOPOLOGY_KIND_QUEUE        = "queue"
+	TOPOLOGY_KIND_BINDING      = "binding"
+
+	// TOPOLOGY_ACTION_NONE is the action of an object that already exists
+	TOPOLOGY_ACTION_NONE = "none"
+	// TOPOLOGY_ACTION_CREATE is the action of an object that is missing
+	TOPOLOGY_ACTION_CREATE = "create"
+	// TOPOLOGY_ACTION_ENSURE is the action of a binding between existing objects. AMQP can't tell whether
+	// a binding exists, so it is declared again, which does nothing when the binding already exists.
+	TOPOLOGY_ACTION_ENSURE = "ensure"
+)
+
+// TopologyChange is what the reconciliation does with an object of the topology.
+type TopologyChange struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// TopologyDiff compares the configured topology with the topology of the RabbitMQ.
+type TopologyDiff struct {
+	DryRun  bool             `json:"dryRun"`
+	Changes []TopologyChange `json:"changes"`
+}
+
+// HasChanges tells whether an object of the topology is missing in the RabbitMQ.
+func (diff *TopologyDiff) HasChanges() bool {
+	for _, change := range diff.Changes {
+		if change.Action == TOPOLOGY_ACTION_CREATE {
+			return true
+		}
+	}
+	return false
+}
+
+func (diff *TopologyDiff) String() string {
+	var changes []string
+	for _, change := range diff.Changes {
+		if change.Action == TOPOLOGY_ACTION_CREATE {
+			changes = append(changes, fmt.Sprintf("%s %s %s", change.Action, change.Kind, change.Name))
+		}
+	}
+	if len(changes) == 0 {
+		return "no changes"
+	}
+	return strings.Join(changes, ", ")
+}
+
+// topologyReconciler creates the missing objects of the topology, one object at a time. A failed
+// declare closes the AMQP channel, so the channel is opened again for the next object.
+type topologyReconciler struct {
+	openChannel func() (TopologyChannel, error)
+	ch          TopologyChannel
+	dryRun      bool
+	diff        *TopologyDiff
+	missing     map[string]bool
+	errs        []error
+}
+
+func newTopologyReconciler(openChannel func() (TopologyChannel, error), dryRun bool) *topologyReconciler {
+	return &topologyReconciler{
+		openChannel: openChannel,
+		dryRun:      dryRun,
+		diff:        &TopologyDiff{DryRun: dryRun, Changes: []TopologyChange{}},
+		missing:     make(map[string]bool),
+	}
+}
+
+// ReconcileTopology compares the configured exchanges, stream, queues and bindings with the RabbitMQ and
+// creates only the missing ones, or only reports them in a dry run. The settings of the existing objects
+// are not compared. Every object is reconciled even when another one failed, and the errors are returned together.
+func (client *RabbitMQStreamClient) ReconcileTopology(dryRun bool) (*TopologyDiff, error) {
+	conn, err := client.createAmqpConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	reconciler := newTopologyReconciler(func() (TopologyChannel, error) {
+		ch, err := conn.Channel()
+		if err != nil {
+			return nil, err
+		}
+		return ch, nil
+	}, dryRun)
+	defer reconciler.close()
+
+	options := client.RabbitMQOptions
+	for _, exchangeOptions := range options.ExchangesOptions {
+		reconciler.reconcileExchange(exchangeOptions)
+	}
+	if options.StreamOptions != nil {
+		if env, err := client.getEnv(); err != nil {
+			reconciler.errs = append(reconciler.errs, err)
+		} else {
+			reconciler.reconcileStream(options.StreamOptions, env)
+		}
+	}
+	for _, queueOptions := range options.QueuesOptions {
+		reconciler.reconcileQueue(queueOptions)
+	}
+	for _, bindingOptions := range options.BindingsOptions {
+		reconciler.reconcileBinding(bindingOptions)
+	}
+	return reconciler.diff, errors.Join(reconciler.errs...)
+}
+
+func (reconciler *topologyReconciler) reconcileExchange(exchangeOptions *ExchangeOptions) {
+	reconciler.reconcile(TOPOLOGY_KIND_EXCHANGE, exchangeOptions.Name,
+		func() (bool, error) { return reconciler.checkOnChannel(exchangeOptions.exists) },
+		func() error { return reconciler.applyOnChannel(exchangeOptions.CreateExchange) },
+	)
+}
+
+func (reconciler *topologyReconciler) reconcileStream(streamOptions *StreamOptions, env StreamTopology) {
+	kind := TOPOLOGY_KIND_STREAM
+	if streamOptions.SuperStream {
+		kind = TOPOLOGY_KIND_SUPER_STREAM
+	}
+	reconciler.reconcile(kind, streamOptions.StreamName,
+		func() (bool, error) { return streamOptions.exists(env) },
+		func() error { return streamOptions.CreateStream(env) },
+	)
+}
+
+func (reconciler *topologyReconciler) reconcileQueue(queueOptions *QueueOptions) {
+	reconciler.reconcile(TOPOLOGY_KIND_QUEUE, queueOptions.Name,
+		func() (bool, error) { return reconciler.checkOnChannel(queueOptions.exists) },
+		func() error { return reconciler.applyOnChannel(queueOptions.CreateQueue) },
+	)
+}
+
+// reconcileBinding declares the binding again, and reports it as missing when its sender or its receiver is missing.
+func (reconciler *topologyReconciler) reconcileBinding(bindingOptions *BindingOptions) {
+	change := TopologyChange{
+		Kind:   TOPOLOGY_KIND_BINDING,
+		Name:   fmt.Sprintf("%s -> %s (%s)", bindingOptions.SenderName, bindingOptions.ReceiverName, bindingOptions.RoutingKey),
+		Action: TOPOLOGY_ACTION_ENSURE,
+	}
+	isReceiverMissing := reconciler.isMissing(TOPOLOGY_KIND_EXCHANGE, bindingOptions.ReceiverName)
+	if bindingOptions.IsQueueBinding {
+		isReceiverMissing = reconciler.isMissing(TOPOLOGY_KIND_QUEUE, bindingOptions.ReceiverName) ||
+			reconciler.isMissing(TOPOLOGY_KIND_STREAM, bindingOptions.ReceiverName)
+	}
+	if isReceiverMissing || reconciler.isMissing(TOPOLOGY_KIND_EXCHANGE, bindingOptions.SenderName) {
+		change.Action = TOPOLOGY_ACTION_CREATE
+	}
+	if !reconciler.dryRun {
+		if err := reconciler.applyOnChannel(bindingOptions.CreateBinding); err != nil {
+			reconciler.fail(&change, fmt.Errorf("failed to create the %s %s: %w", change.Kind, change.Name, err))
+		}
+	}
+	reconciler.diff.Changes = append(reconciler.diff.Changes, change)
+}
+
+// reconcile checks whether the object exists, and creates it when it is missing and it is not a dry run.
+func (reconciler *topologyReconciler) reconcile(kind string, name string, exists func() (bool, error), create func() error) {
+	change := TopologyChange{Kind: kind, Name: name, Action: TOPOLOGY_ACTION_NONE}
+	found, err := exists()
+	if err != nil {
+		reconciler.fail(&change, fmt.Errorf("failed to check the %s %s: %w", kind, name, err))
+	} else if !found {
+		change.Action = TOPOLOGY_ACTION_CREATE
+		reconciler.missing[kind+":"+name] = true
+		if !reconciler.dryRun {
+			if err := create(); err != nil {
+				reconciler.fail(&change, fmt.Errorf("failed to create the %s %s: %w", kind, name, err))
+			} else {
+				log.DefaultLogger.Info("Created a missing object of the RabbitMQ topology", "kind", kind, "name", name)
+			}
+		}
+	}
+	reconciler.diff.Changes = append(reconciler.diff.Changes, change)
+}
+
+func (reconciler *topologyReconciler) isMissing(kind string, name string) bool {
+	return reconciler.missing[kind+":"+name]
+}
+
+func (reconciler *topologyReconciler) fail(change *TopologyChange, err error) {
+	change.Error = err.Error()
+	reconciler.errs = append(reconciler.errs, err)
+}
+
+func (reconciler *topologyReconciler) channel() (TopologyChannel, error) {
+	if reconciler.ch == nil {
+		ch, err := reconciler.openChannel()
+		if err != nil {
+			return nil, err
+		}
+		reconciler.ch = ch
+	}
+	return reconciler.ch, nil
+}
+
+// checkOnChannel runs a passive declare, the broker closes the channel when the object is missing.
+func (reconciler *topologyReconciler) checkOnChannel(exists func(TopologyChannel) (bool, error)) (bool, error) {
+	ch, err := reconciler.channel()
+	if err != nil {
+		return false, err
+	}
+	found, err := exists(ch)
+	if !found {
+		reconciler.resetChannel()
+	}
+	return found, err
+}
+
+// applyOnChannel declares an object, the broker closes the channel when the declare fails.
+func (reconciler *topologyReconciler) applyOnChannel(apply func(TopologyChannel) error) error {
+	ch, err := reconciler.channel()
+	if err != nil {
+		return err
+	}
+	if err := apply(ch); err != nil {
+		reconciler.resetChannel()
+		return err
+	}
+	return nil
+}
+
+func (reconciler *topologyReconciler) resetChannel() {
+	if reconciler.ch != nil {
+		reconciler.ch.Close()
+		reconciler.ch = nil
+	}
+}
+
+func (reconciler *topologyReconciler) close() {
+	reconciler.resetChannel()
+}
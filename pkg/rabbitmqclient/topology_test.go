@@ -0,0 +1,556 @@
+package rabbitmqclient
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+)
+
+// fakeCall is a call of the fake channel, with the arguments that were passed to the channel.
+type fakeCall struct {
+	method string
+	args   []interface{}
+}
+
+// fakeChannel records the topology calls instead of sending them to a broker, fails the calls
+// of the methods in failures, and answers the passive declares with the existing objects.
+type fakeChannel struct {
+	calls    []fakeCall
+	failures map[string]error
+	existing map[string]bool
+}
+
+func (ch *fakeChannel) record(method string, args ...interface{}) error {
+	ch.calls = append(ch.calls, fakeCall{method: method, args: args})
+	return ch.failures[method]
+}
+
+func (ch *fakeChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	return ch.record("ExchangeDeclare", name, kind, durable, autoDelete, internal, noWait, args)
+}
+
+func (ch *fakeChannel) ExchangeDeclarePassive(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	if !ch.existing[name] {
+		return &amqp.Error{Code: amqp.NotFound, Reason: "NOT_FOUND - no exchange '" + name + "'"}
+	}
+	return nil
+}
+
+func (ch *fakeChannel) ExchangeDelete(name string, ifUnused, noWait bool) error {
+	return ch.record("ExchangeDelete", name, ifUnused, noWait)
+}
+
+func (ch *fakeChannel) ExchangeBind(destination, key, source string, noWait bool, args amqp.Table) error {
+	return ch.record("ExchangeBind", destination, key, source, noWait, args)
+}
+
+func (ch *fakeChannel) ExchangeUnbind(destination, key, source string, noWait bool, args amqp.Table) error {
+	return ch.record("ExchangeUnbind", destination, key, source, noWait, args)
+}
+
+func (ch *fakeChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	return amqp.Queue{Name: name}, ch.record("QueueDeclare", name, durable, autoDelete, exclusive, noWait, args)
+}
+
+func (ch *fakeChannel) QueueDeclarePassive(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	if !ch.existing[name] {
+		return amqp.Queue{}, &amqp.Error{Code: amqp.NotFound, Reason: "NOT_FOUND - no queue '" + name + "'"}
+	}
+	return amqp.Queue{Name: name}, nil
+}
+
+func (ch *fakeChannel) QueueDelete(name string, ifUnused, ifEmpty, noWait bool) (int, error) {
+	return 0, ch.record("QueueDelete", name, ifUnused, ifEmpty, noWait)
+}
+
+func (ch *fakeChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	return ch.record("QueueBind", name, key, exchange, noWait, args)
+}
+
+func (ch *fakeChannel) QueueUnbind(name, key, exchange string, args amqp.Table) error {
+	return ch.record("QueueUnbind", name, key, exchange, args)
+}
+
+func (ch *fakeChannel) Close() error {
+	return nil
+}
+
+func assertCalls(t *testing.T, ch *fakeChannel, expectedCalls []fakeCall) {
+	t.Helper()
+	if len(ch.calls) == 0 && len(expectedCalls) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(ch.calls, expectedCalls) {
+		t.Errorf("unexpected channel calls:\n got: %+v\nwant: %+v", ch.calls, expectedCalls)
+	}
+}
+
+func TestCreateExchange(t *testing.T) {
+	ch := &fakeChannel{}
+	exchangeOptions := &ExchangeOptions{
+		Name:              "rabbitmq.exchange",
+		Type:              "topic",
+		Durable:           true,
+		AlternateExchange: "rabbitmq.unrouted",
+		Arguments:         map[string]interface{}{"x-custom": float64(5)},
+	}
+
+	if err := exchangeOptions.CreateExchange(ch); err != nil {
+		t.Fatalf("CreateExchange returned an error: %v", err)
+	}
+	assertCalls(t, ch, []fakeCall{{
+		method: "ExchangeDeclare",
+		args: []interface{}{"rabbitmq.exchange", "topic", true, false, false, false,
+			amqp.Table{"x-custom": int64(5), "alternate-exchange": "rabbitmq.unrouted"}},
+	}})
+}
+
+func TestCreateQueue(t *testing.T) {
+	tests := []struct {
+		name          string
+		queueOptions  *QueueOptions
+		expectedCalls []fakeCall
+	}{
+		{
+			name:         "no arguments",
+			queueOptions: &QueueOptions{Name: "rabbitmq.queue", Durable: true},
+			expectedCalls: []fakeCall{{
+				method: "QueueDeclare",
+				args:   []interface{}{"rabbitmq.queue", true, false, false, false, amqp.Table(nil)},
+			}},
+		},
+		{
+			name: "x-arguments",
+			queueOptions: &QueueOptions{
+				Name:                 "rabbitmq.queue",
+				Type:                 QUEUE_TYPE_QUORUM,
+				Durable:              true,
+				MessageTTL:           60000,
+				MaxLength:            1000,
+				MaxLengthBytes:       1048576,
+				DeadLetterExchange:   "rabbitmq.dead",
+				DeadLetterRoutingKey: "expired",
+				Overflow:             QUEUE_OVERFLOW_REJECT_PUBLISH,
+				Arguments:            map[string]interface{}{"x-delivery-limit": float64(5)},
+			},
+			expectedCalls: []fakeCall{{
+				method: "QueueDeclare",
+				args: []interface{}{"rabbitmq.queue", true, false, false, false, amqp.Table{
+					"x-queue-type":              "quorum",
+					"x-message-ttl":             int64(60000),
+					"x-max-length":              int64(1000),
+					"x-max-length-bytes":        int64(1048576),
+					"x-dead-letter-exchange":    "rabbitmq.dead",
+					"x-dead-letter-routing-key": "expired",
+					"x-overflow":                "reject-publish",
+					"x-delivery-limit":          int64(5),
+				}},
+			}},
+		},
+		{
+			name: "fields override the arguments",
+			queueOptions: &QueueOptions{
+				Name:        "rabbitmq.queue",
+				Exclusive:   true,
+				AutoDeleted: true,
+				MessageTTL:  1000,
+				Arguments:   map[string]interface{}{"x-message-ttl": float64(5)},
+			},
+			expectedCalls: []fakeCall{{
+				method: "QueueDeclare",
+				args:   []interface{}{"rabbitmq.queue", false, true, true, false, amqp.Table{"x-message-ttl": int64(1000)}},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ch := &fakeChannel{}
+			if err := test.queueOptions.CreateQueue(ch); err != nil {
+				t.Fatalf("CreateQueue returned an error: %v", err)
+			}
+			assertCalls(t, ch, test.expectedCalls)
+		})
+	}
+}
+
+func TestDisposeExchange(t *testing.T) {
+	tests := []struct {
+		name            string
+		exchangeOptions *ExchangeOptions
+		expectedCalls   []fakeCall
+	}{
+		{
+			name:            "should dispose",
+			exchangeOptions: &ExchangeOptions{Name: "rabbitmq.exchange", ShouldDisposeExchange: true, DisposeIfUnused: true},
+			expectedCalls:   []fakeCall{{method: "ExchangeDelete", args: []interface{}{"rabbitmq.exchange", true, false}}},
+		},
+		{
+			name:            "should not dispose",
+			exchangeOptions: &ExchangeOptions{Name: "rabbitmq.exchange"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ch := &fakeChannel{}
+			if err := test.exchangeOptions.DisposeExchange(ch); err != nil {
+				t.Fatalf("DisposeExchange returned an error: %v", err)
+			}
+			assertCalls(t, ch, test.expectedCalls)
+		})
+	}
+}
+
+func TestCreateBinding(t *testing.T) {
+	tests := []struct {
+		name           string
+		bindingOptions *BindingOptions
+		expectedCalls  []fakeCall
+	}{
+		{
+			name: "queue binding",
+			bindingOptions: &BindingOptions{
+				SenderName: "rabbitmq.exchange", RoutingKey: "/", ReceiverName: "rabbitmq.stream", IsQueueBinding: true,
+			},
+			expectedCalls: []fakeCall{{
+				method: "QueueBind",
+				args:   []interface{}{"rabbitmq.stream", "/", "rabbitmq.exchange", false, amqp.Table(nil)},
+			}},
+		},
+		{
+			name: "exchange binding",
+			bindingOptions: &BindingOptions{
+				SenderName: "rabbitmq.exchange", RoutingKey: "/", ReceiverName: "rabbitmq.receiver",
+				Arguments: map[string]interface{}{"x-match": "all"},
+			},
+			expectedCalls: []fakeCall{{
+				method: "ExchangeBind",
+				args:   []interface{}{"rabbitmq.receiver", "/", "rabbitmq.exchange", false, amqp.Table{"x-match": "all"}},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ch := &fakeChannel{}
+			if err := test.bindingOptions.CreateBinding(ch); err != nil {
+				t.Fatalf("CreateBinding returned an error: %v", err)
+			}
+			assertCalls(t, ch, test.expectedCalls)
+		})
+	}
+}
+
+func TestDisposeBinding(t *testing.T) {
+	tests := []struct {
+		name           string
+		bindingOptions *BindingOptions
+		expectedCalls  []fakeCall
+	}{
+		{
+			name: "queue binding",
+			bindingOptions: &BindingOptions{
+				SenderName: "rabbitmq.exchange", RoutingKey: "/", ReceiverName: "rabbitmq.stream",
+				IsQueueBinding: true, ShouldDisposeBinding: true,
+			},
+			expectedCalls: []fakeCall{{
+				method: "QueueUnbind",
+				args:   []interface{}{"rabbitmq.stream", "/", "rabbitmq.exchange", amqp.Table(nil)},
+			}},
+		},
+		{
+			name: "exchange binding",
+			bindingOptions: &BindingOptions{
+				SenderName: "rabbitmq.exchange", RoutingKey: "/", ReceiverName: "rabbitmq.receiver",
+				NoWait: true, ShouldDisposeBinding: true,
+			},
+			expectedCalls: []fakeCall{{
+				method: "ExchangeUnbind",
+				args:   []interface{}{"rabbitmq.receiver", "/", "rabbitmq.exchange", true, amqp.Table(nil)},
+			}},
+		},
+		{
+			name: "should not dispose",
+			bindingOptions: &BindingOptions{
+				SenderName: "rabbitmq.exchange", RoutingKey: "/", ReceiverName: "rabbitmq.receiver",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ch := &fakeChannel{}
+			if err := test.bindingOptions.DisposeBinding(ch); err != nil {
+				t.Fatalf("DisposeBinding returned an error: %v", err)
+			}
+			assertCalls(t, ch, test.expectedCalls)
+		})
+	}
+}
+
+// TestTopologyLifecycle creates the topology of the client and disposes it again,
+// the bindings are created last and removed first.
+func TestTopologyLifecycle(t *testing.T) {
+	client := &RabbitMQStreamClient{RabbitMQOptions: &RabbitMQStreamOptions{
+		ExchangesOptions: []*ExchangeOptions{
+			{Name: "rabbitmq.exchange", Type: "fanout", Durable: true, ShouldDisposeExchange: true},
+			{Name: "rabbitmq.receiver", Type: "fanout", Durable: true, ShouldDisposeExchange: true},
+		},
+		BindingsOptions: []*BindingOptions{
+			{SenderName: "rabbitmq.exchange", RoutingKey: "/", ReceiverName: "rabbitmq.receiver", ShouldDisposeBinding: true},
+		},
+	}}
+	client.SetExchanges().SetBindings()
+
+	ch := &fakeChannel{}
+	if _, err := client.CreateExchanges(ch); err != nil {
+		t.Fatalf("CreateExchanges returned an error: %v", err)
+	}
+	if _, err := client.CreateBindings(ch); err != nil {
+		t.Fatalf("CreateBindings returned an error: %v", err)
+	}
+	if _, err := client.DisposeBindings(ch); err != nil {
+		t.Fatalf("DisposeBindings returned an error: %v", err)
+	}
+	if _, err := client.DisposeExchanges(ch); err != nil {
+		t.Fatalf("DisposeExchanges returned an error: %v", err)
+	}
+
+	methods := make([]string, len(ch.calls))
+	for index, call := range ch.calls {
+		methods[index] = call.method
+	}
+	expectedMethods := []string{"ExchangeDeclare", "ExchangeDeclare", "ExchangeBind", "ExchangeUnbind", "ExchangeDelete", "ExchangeDelete"}
+	if !reflect.DeepEqual(methods, expectedMethods) {
+		t.Errorf("unexpected topology lifecycle: got %v, want %v", methods, expectedMethods)
+	}
+}
+
+func TestCreateExchangesStopsOnError(t *testing.T) {
+	expectedErr := errors.New("access refused")
+	client := &RabbitMQStreamClient{RabbitMQOptions: &RabbitMQStreamOptions{
+		ExchangesOptions: []*ExchangeOptions{
+			{Name: "rabbitmq.exchange", Type: "fanout"},
+			{Name: "rabbitmq.receiver", Type: "fanout"},
+		},
+	}}
+	client.SetExchanges()
+
+	ch := &fakeChannel{failures: map[string]error{"ExchangeDeclare": expectedErr}}
+	if _, err := client.CreateExchanges(ch); !errors.Is(err, expectedErr) {
+		t.Fatalf("CreateExchanges returned %v, want %v", err, expectedErr)
+	}
+	if len(ch.calls) != 1 {
+		t.Errorf("CreateExchanges kept declaring after an error: %d calls", len(ch.calls))
+	}
+}
+
+// newFakeReconciler opens the channels of the reconciler on the fake broker, which shares its calls and its objects.
+func newFakeReconciler(broker *fakeChannel, dryRun bool) (*topologyReconciler, *int) {
+	openedChannels := 0
+	return newTopologyReconciler(func() (TopologyChannel, error) {
+		openedChannels += 1
+		return broker, nil
+	}, dryRun), &openedChannels
+}
+
+func TestReconcileTopology(t *testing.T) {
+	broker := &fakeChannel{existing: map[string]bool{"rabbitmq.exchange": true}}
+	reconciler, openedChannels := newFakeReconciler(broker, false)
+
+	reconciler.reconcileExchange(&ExchangeOptions{Name: "rabbitmq.exchange", Type: "fanout"})
+	reconciler.reconcileExchange(&ExchangeOptions{Name: "rabbitmq.receiver", Type: "fanout"})
+	reconciler.reconcileQueue(&QueueOptions{Name: "rabbitmq.queue", Durable: true})
+	reconciler.reconcileBinding(&BindingOptions{SenderName: "rabbitmq.exchange", RoutingKey: "/", ReceiverName: "rabbitmq.queue", IsQueueBinding: true})
+	reconciler.reconcileBinding(&BindingOptions{SenderName: "rabbitmq.exchange", RoutingKey: "/", ReceiverName: "rabbitmq.other", IsQueueBinding: true})
+	reconciler.close()
+
+	if len(reconciler.errs) != 0 {
+		t.Fatalf("the reconciliation failed: %v", reconciler.errs)
+	}
+	actions := make([]string, len(reconciler.diff.Changes))
+	for index, change := range reconciler.diff.Changes {
+		actions[index] = change.Action
+	}
+	expectedActions := []string{TOPOLOGY_ACTION_NONE, TOPOLOGY_ACTION_CREATE, TOPOLOGY_ACTION_CREATE, TOPOLOGY_ACTION_CREATE, TOPOLOGY_ACTION_ENSURE}
+	if !reflect.DeepEqual(actions, expectedActions) {
+		t.Errorf("unexpected actions: got %v, want %v", actions, expectedActions)
+	}
+	if !reconciler.diff.HasChanges() {
+		t.Errorf("the diff has no changes")
+	}
+
+	methods := make([]string, len(broker.calls))
+	for index, call := range broker.calls {
+		methods[index] = call.method
+	}
+	// the existing exchange is not declared again, but the bindings are
+	expectedMethods := []string{"ExchangeDeclare", "QueueDeclare", "QueueBind", "QueueBind"}
+	if !reflect.DeepEqual(methods, expectedMethods) {
+		t.Errorf("unexpected channel calls: got %v, want %v", methods, expectedMethods)
+	}
+	// a passive declare of a missing object closes the channel
+	if *openedChannels != 3 {
+		t.Errorf("unexpected number of opened channels: got %d, want 3", *openedChannels)
+	}
+}
+
+func TestReconcileTopologyDryRun(t *testing.T) {
+	broker := &fakeChannel{existing: map[string]bool{"rabbitmq.exchange": true}}
+	reconciler, _ := newFakeReconciler(broker, true)
+
+	reconciler.reconcileExchange(&ExchangeOptions{Name: "rabbitmq.exchange", Type: "fanout"})
+	reconciler.reconcileExchange(&ExchangeOptions{Name: "rabbitmq.receiver", Type: "fanout"})
+	reconciler.reconcileBinding(&BindingOptions{SenderName: "rabbitmq.exchange", RoutingKey: "/", ReceiverName: "rabbitmq.receiver"})
+
+	assertCalls(t, broker, nil)
+	if diff := reconciler.diff.String(); diff != "create exchange rabbitmq.receiver, create binding rabbitmq.exchange -> rabbitmq.receiver (/)" {
+		t.Errorf("unexpected diff: %s", diff)
+	}
+}
+
+func TestReconcileTopologyKeepsGoingOnError(t *testing.T) {
+	expectedErr := errors.New("access refused")
+	broker := &fakeChannel{failures: map[string]error{"ExchangeDeclare": expectedErr}}
+	reconciler, _ := newFakeReconciler(broker, false)
+
+	reconciler.reconcileExchange(&ExchangeOptions{Name: "rabbitmq.exchange", Type: "fanout"})
+	reconciler.reconcileQueue(&QueueOptions{Name: "rabbitmq.queue", Durable: true})
+
+	if len(reconciler.errs) != 1 || !errors.Is(reconciler.errs[0], expectedErr) {
+		t.Fatalf("unexpected errors: %v", reconciler.errs)
+	}
+	if reconciler.diff.Changes[0].Error == "" || reconciler.diff.Changes[1].Error != "" {
+		t.Errorf("unexpected errors in the diff: %+v", reconciler.diff.Changes)
+	}
+	if len(broker.calls) != 2 || broker.calls[1].method != "QueueDeclare" {
+		t.Errorf("the queue was not created after the exchange failed: %+v", broker.calls)
+	}
+}
+
+// fakeStreamTopology answers like a broker with the existing streams and super streams, and records the declared ones.
+type fakeStreamTopology struct {
+	streams      map[string]bool
+	superStreams map[string][]string
+	declared     []string
+}
+
+func (env *fakeStreamTopology) StreamExists(streamName string) (bool, error) {
+	return env.streams[streamName], nil
+}
+
+func (env *fakeStreamTopology) QueryPartitions(superStreamName string) ([]string, error) {
+	partitions, exists := env.superStreams[superStreamName]
+	if !exists {
+		return nil, stream.StreamDoesNotExist
+	}
+	return partitions, nil
+}
+
+func (env *fakeStreamTopology) DeclareStream(streamName string, _ *stream.StreamOptions) error {
+	env.declared = append(env.declared, streamName)
+	return nil
+}
+
+func (env *fakeStreamTopology) DeclareSuperStream(superStreamName string, _ stream.SuperStreamOptions) error {
+	env.declared = append(env.declared, superStreamName)
+	return nil
+}
+
+func TestReconcileStream(t *testing.T) {
+	tests := []struct {
+		name             string
+		streamOptions    *StreamOptions
+		expectedAction   string
+		expectedDeclared []string
+	}{
+		{
+			name:           "existing stream",
+			streamOptions:  &StreamOptions{StreamName: "rabbitmq.stream"},
+			expectedAction: TOPOLOGY_ACTION_NONE,
+		},
+		{
+			name:             "missing stream",
+			streamOptions:    &StreamOptions{StreamName: "rabbitmq.missing"},
+			expectedAction:   TOPOLOGY_ACTION_CREATE,
+			expectedDeclared: []string{"rabbitmq.missing"},
+		},
+		{
+			name:           "existing super stream",
+			streamOptions:  &StreamOptions{StreamName: "rabbitmq.super", SuperStream: true},
+			expectedAction: TOPOLOGY_ACTION_NONE,
+		},
+		{
+			name:             "missing super stream",
+			streamOptions:    &StreamOptions{StreamName: "rabbitmq.missing", SuperStream: true, PartitionCount: 3},
+			expectedAction:   TOPOLOGY_ACTION_CREATE,
+			expectedDeclared: []string{"rabbitmq.missing"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := &fakeStreamTopology{
+				streams:      map[string]bool{"rabbitmq.stream": true},
+				superStreams: map[string][]string{"rabbitmq.super": {"rabbitmq.super-0"}},
+			}
+			reconciler, _ := newFakeReconciler(&fakeChannel{}, false)
+			reconciler.reconcileStream(test.streamOptions, env)
+
+			if len(reconciler.errs) != 0 {
+				t.Fatalf("the reconciliation failed: %v", reconciler.errs)
+			}
+			if action := reconciler.diff.Changes[0].Action; action != test.expectedAction {
+				t.Errorf("unexpected action: got %s, want %s", action, test.expectedAction)
+			}
+			if !reflect.DeepEqual(env.declared, test.expectedDeclared) {
+				t.Errorf("unexpected declared streams: got %v, want %v", env.declared, test.expectedDeclared)
+			}
+		})
+	}
+}
+
+func TestValidateQueue(t *testing.T) {
+	tests := []struct {
+		name         string
+		queueOptions *QueueOptions
+		expectError  bool
+	}{
+		{
+			name:         "classic queue",
+			queueOptions: &QueueOptions{Name: "rabbitmq.queue", Overflow: QUEUE_OVERFLOW_DROP_HEAD},
+		},
+		{
+			name:         "durable quorum queue",
+			queueOptions: &QueueOptions{Name: "rabbitmq.queue", Type: QUEUE_TYPE_QUORUM, Durable: true},
+		},
+		{
+			// the broker would declare a new queue with a generated name on every connection
+			name:         "queue without a name",
+			queueOptions: &QueueOptions{Durable: true},
+			expectError:  true,
+		},
+		{
+			name:         "exclusive quorum queue",
+			queueOptions: &QueueOptions{Name: "rabbitmq.queue", Type: QUEUE_TYPE_QUORUM, Durable: true, Exclusive: true},
+			expectError:  true,
+		},
+		{
+			name:         "unknown type",
+			queueOptions: &QueueOptions{Name: "rabbitmq.queue", Type: "lazy"},
+			expectError:  true,
+		},
+		{
+			name:         "negative max length",
+			queueOptions: &QueueOptions{Name: "rabbitmq.queue", MaxLength: -1},
+			expectError:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.queueOptions.Validate()
+			if (err != nil) != test.expectError {
+				t.Errorf("unexpected validation error: got %v, want an error: %v", err, test.expectError)
+			}
+		})
+	}
+}
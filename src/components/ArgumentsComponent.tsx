@@ -0,0 +1,40 @@
+import React, { useState } from 'react';
+
+import { InlineField, TextArea } from '@grafana/ui';
+
+import { Arguments } from '../types';
+import { LABEL_WIDTH, INPUT_WIDTH } from './consts';
+
+
+export function ArgumentsComponent({ args, setArgs, tooltip }: { args?: Arguments, setArgs: (args?: Arguments) => void, tooltip: string }) {
+    const [isInvalid, setIsInvalid] = useState<boolean>(false);
+
+    const onArgsChange = (value: string) => {
+        if (value.trim() === '') {
+            setIsInvalid(false);
+            setArgs(undefined);
+            return;
+        }
+        try {
+            const parsedArgs = JSON.parse(value);
+            const isObject = typeof parsedArgs === 'object' && parsedArgs !== null && !Array.isArray(parsedArgs);
+            setIsInvalid(!isObject);
+            if (isObject) {
+                setArgs(parsedArgs);
+            }
+        } catch {
+            setIsInvalid(true);
+        }
+    };
+
+    return (
+        <InlineField label="Arguments" labelWidth={LABEL_WIDTH} tooltip={tooltip} invalid={isInvalid} error="The arguments must be a JSON object">
+            <TextArea
+                onBlur={(event) => onArgsChange(event.currentTarget.value)}
+                defaultValue={args ? JSON.stringify(args) : ''}
+                placeholder='{"x-argument": "value"}'
+                cols={INPUT_WIDTH}
+            />
+        </InlineField>
+    );
+}
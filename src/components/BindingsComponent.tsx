@@ -2,7 +2,8 @@ import React from 'react';
 
 import { InlineField, InlineSwitch, Input, Button } from '@grafana/ui';
 
-import { BindingsOptions, BindingOptions } from '../types';
+import { BindingsOptions, BindingOptions, Arguments } from '../types';
+import { ArgumentsComponent } from './ArgumentsComponent';
 import { LABEL_WIDTH, INPUT_WIDTH, SWITCH_WIDTH } from './consts';
 
 
@@ -27,7 +28,7 @@ export function BindingsComponent({ bindings, setBindings }: { bindings: Binding
         setBindings(prevBindings => [...prevBindings, newBinding]);
     }
 
-    const updatebindingProperty = (index: number, property: keyof BindingOptions, value: string | boolean) => {
+    const updatebindingProperty = (index: number, property: keyof BindingOptions, value: string | boolean | Arguments | undefined) => {
         setBindings(prevbindings =>
           prevbindings.map((binding, i) => (i === index ? { ...binding, [property]: value } : binding))
         );
@@ -84,6 +85,11 @@ export function BindingsComponent({ bindings, setBindings }: { bindings: Binding
                         width={SWITCH_WIDTH}
                     />
                 </InlineField>
+                <ArgumentsComponent
+                    args={value.arguments}
+                    setArgs={args => updatebindingProperty(index, 'arguments', args)}
+                    tooltip="The arguments of the binding as a JSON object, like the headers to match in a binding of a headers exchange (with x-match)"
+                />
                 <Button variant="secondary" fill="text" icon="minus" onClick={() => removebinding(index)} tooltip="Remove" aria-label="Remove" />
             </>
             ))
@@ -2,7 +2,8 @@ import React from 'react';
 
 import { InlineField, InlineSwitch, Input, Button, RadioButtonGroup } from '@grafana/ui';
 
-import { ExchangesOptions, ExchangeOptions } from '../types';
+import { ExchangesOptions, ExchangeOptions, Arguments } from '../types';
+import { ArgumentsComponent } from './ArgumentsComponent';
 import { LABEL_WIDTH, INPUT_WIDTH, SWITCH_WIDTH } from './consts';
 
 
@@ -50,7 +51,7 @@ export function ExchangesComponent({ exchanges, setExchanges }: { exchanges: Exc
       },
     ];
 
-    const updateExchangeProperty = (index: number, property: keyof ExchangeOptions, value: string | boolean) => {
+    const updateExchangeProperty = (index: number, property: keyof ExchangeOptions, value: string | boolean | Arguments | undefined) => {
         setExchanges(prevExchanges =>
           prevExchanges.map((exchange, i) => (i === index ? { ...exchange, [property]: value } : exchange))
         );
@@ -117,6 +118,18 @@ export function ExchangesComponent({ exchanges, setExchanges }: { exchanges: Exc
                         width={SWITCH_WIDTH}
                     />
                 </InlineField>
+                <InlineField label="Alternate Exchange" labelWidth={LABEL_WIDTH} tooltip="The exchange of the messages that can't be routed by this exchange (alternate-exchange)">
+                    <Input
+                        onChange={ event => updateExchangeProperty(index, 'alternateExchange', event.currentTarget.value || undefined)}
+                        value={value.alternateExchange ?? ''}
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <ArgumentsComponent
+                    args={value.arguments}
+                    setArgs={args => updateExchangeProperty(index, 'arguments', args)}
+                    tooltip="Any other argument of the exchange, as a JSON object"
+                />
                 <Button variant="secondary" fill="text" icon="minus" onClick={() => removeExchange(index)} tooltip="Remove" aria-label="Remove" />
             </>
             ))
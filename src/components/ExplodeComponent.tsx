@@ -0,0 +1,31 @@
+import React from 'react';
+
+import { InlineField, InlineSwitch, Input } from '@grafana/ui';
+
+import { ExplodeOptions } from '../types';
+import { LABEL_WIDTH, INPUT_WIDTH, SWITCH_WIDTH } from './consts';
+
+
+export function ExplodeComponent({ explodeOptions, setExplodeOptions }: { explodeOptions?: ExplodeOptions, setExplodeOptions: (explodeOptions?: ExplodeOptions) => void }) {
+    return (
+    <>
+        <InlineField label="Explode Array" labelWidth={LABEL_WIDTH} tooltip="Frame every item of an array in the messages as a row of its own, for the producers that batch several samples into a single message">
+            <InlineSwitch
+                onChange={(event) => setExplodeOptions(event.currentTarget.checked ? {} : undefined)}
+                value={explodeOptions !== undefined}
+                width={SWITCH_WIDTH}
+            />
+        </InlineField>
+        {explodeOptions && (
+            <InlineField label="Array Path" labelWidth={LABEL_WIDTH} tooltip="A JSONPath expression that selects the array, like $.samples (leave empty when the message itself is the array)">
+                <Input
+                    onBlur={(event) => setExplodeOptions({ ...explodeOptions, path: event.currentTarget.value || undefined })}
+                    defaultValue={explodeOptions.path ?? ''}
+                    placeholder="Path (can be empty)"
+                    width={INPUT_WIDTH}
+                />
+            </InlineField>
+        )}
+    </>
+    );
+}
@@ -0,0 +1,81 @@
+import React from 'react';
+
+import { InlineField, Input, Button, RadioButtonGroup } from '@grafana/ui';
+
+import { Extraction } from '../types';
+import { LABEL_WIDTH, INPUT_WIDTH } from './consts';
+
+
+const languages = [
+    { label: 'JSONPath', value: 'jsonpath' },
+    { label: 'JMESPath', value: 'jmespath' },
+];
+
+export const fieldTypes = [
+    { label: 'Auto', value: 'auto' },
+    { label: 'Number', value: 'number' },
+    { label: 'String', value: 'string' },
+    { label: 'Boolean', value: 'boolean' },
+    { label: 'Time', value: 'time' },
+    { label: 'JSON', value: 'json' },
+];
+
+export function ExtractionsComponent({ extractions, setExtractions }: { extractions?: Extraction[], setExtractions: (extractions?: Extraction[]) => void }) {
+    const DEFAULT_EXPRESSION = "$.value";
+
+    const addExtraction = () => {
+        setExtractions([...(extractions ?? []), { expression: DEFAULT_EXPRESSION }]);
+    }
+
+    const updateExtractionProperty = (index: number, property: keyof Extraction, value: string | undefined) => {
+        setExtractions((extractions ?? []).map((extraction, i) => (i === index ? { ...extraction, [property]: value } : extraction)));
+    };
+
+    const removeExtraction = (index: number) => {
+        const remainingExtractions = (extractions ?? []).filter((_, i) => i !== index);
+        setExtractions(remainingExtractions.length > 0 ? remainingExtractions : undefined);
+    }
+
+    return (
+    <>
+        {
+        (extractions ?? []).map((value, index) => (
+            <>
+                <InlineField label="Expression" labelWidth={LABEL_WIDTH} tooltip="The expression that extracts the field out of the messages, like $.payload.temperature (JSONPath) or payload.temperature (JMESPath)">
+                    <Input
+                        onBlur={ event => updateExtractionProperty(index, 'expression', event.currentTarget.value || DEFAULT_EXPRESSION)}
+                        defaultValue={value.expression}
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Language" labelWidth={LABEL_WIDTH} tooltip="The language of the expression">
+                    <RadioButtonGroup
+                        options={languages}
+                        value={value.language ?? 'jsonpath'}
+                        onChange={ language => updateExtractionProperty(index, 'language', language)}
+                    />
+                </InlineField>
+                <InlineField label="Alias" labelWidth={LABEL_WIDTH} tooltip="The name of the field (leave empty to name the field by its expression)">
+                    <Input
+                        onBlur={ event => updateExtractionProperty(index, 'alias', event.currentTarget.value || undefined)}
+                        defaultValue={value.alias ?? ''}
+                        placeholder="Alias (can be empty)"
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Type" labelWidth={LABEL_WIDTH} tooltip="Converts the extracted value, a time is parsed from an RFC3339 string or from milliseconds since the epoch (auto keeps the type of the JSON value)">
+                    <RadioButtonGroup
+                        options={fieldTypes}
+                        value={value.type ?? 'auto'}
+                        onChange={ type => updateExtractionProperty(index, 'type', type)}
+                    />
+                </InlineField>
+                <Button variant="secondary" fill="text" icon="minus" onClick={() => removeExtraction(index)} tooltip="Remove" aria-label="Remove" />
+            </>
+            ))
+        }
+        <InlineField label="Extractions" labelWidth={LABEL_WIDTH} tooltip="Only frame the extracted fields instead of every key of the messages">
+            <Button variant="secondary" fill="text" icon="plus" onClick={addExtraction} tooltip="Add" aria-label="Add" />
+        </InlineField>
+    </>)
+};
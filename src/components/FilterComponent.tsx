@@ -0,0 +1,46 @@
+import React from 'react';
+
+import { InlineField, InlineSwitch, Input, TagsInput } from '@grafana/ui';
+
+import { StreamFilterOptions } from '../types';
+import { LABEL_WIDTH, INPUT_WIDTH, SWITCH_WIDTH } from './consts';
+
+
+export function FilterComponent({ filterOptions, setFilterOptions }: { filterOptions?: StreamFilterOptions, setFilterOptions: (filterOptions?: StreamFilterOptions) => void }) {
+    const updateFilterProperty = (property: keyof StreamFilterOptions, value: string | string[] | boolean | undefined) => {
+        const updatedFilterOptions = { values: [], ...filterOptions, [property]: value };
+        setFilterOptions(updatedFilterOptions.values.length > 0 ? updatedFilterOptions : undefined);
+    };
+
+    return (
+    <>
+        <InlineField label="Filter Values" labelWidth={LABEL_WIDTH} tooltip="Only receive the chunks of the stream that contain messages with one of the filter values (requires RabbitMQ 3.13 or later)">
+            <TagsInput
+                onChange={(values) => updateFilterProperty('values', values)}
+                tags={filterOptions?.values ?? []}
+                placeholder="New filter value (enter key to add)"
+                width={INPUT_WIDTH}
+            />
+        </InlineField>
+        {(filterOptions?.values?.length ?? 0) > 0 && (
+            <>
+                <InlineField label="Filter Property" labelWidth={LABEL_WIDTH} tooltip="The application property that holds the filter value of the message, used to filter out the messages of other values in the received chunks (leave empty to keep the whole chunks)">
+                    <Input
+                        onBlur={(event) => updateFilterProperty('property', event.currentTarget.value || undefined)}
+                        defaultValue={filterOptions?.property ?? ''}
+                        placeholder="Property (can be empty)"
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Match Unfiltered" labelWidth={LABEL_WIDTH} tooltip="Also receive the messages that were published without a filter value">
+                    <InlineSwitch
+                        onChange={(event) => updateFilterProperty('matchUnfiltered', event.currentTarget.checked)}
+                        value={filterOptions?.matchUnfiltered ?? false}
+                        width={SWITCH_WIDTH}
+                    />
+                </InlineField>
+            </>
+        )}
+    </>
+    );
+}
@@ -0,0 +1,57 @@
+import React from 'react';
+
+import { InlineField, InlineSwitch, Input, TagsInput } from '@grafana/ui';
+
+import { FlattenOptions } from '../types';
+import { LABEL_WIDTH, INPUT_WIDTH, SWITCH_WIDTH } from './consts';
+
+
+export function FlattenComponent({ flattenOptions, setFlattenOptions }: { flattenOptions?: FlattenOptions, setFlattenOptions: (flattenOptions?: FlattenOptions) => void }) {
+    const updateFlattenProperty = (property: keyof FlattenOptions, value: string | string[] | number | undefined) => {
+        setFlattenOptions({ ...flattenOptions, [property]: value });
+    };
+
+    return (
+    <>
+        <InlineField label="Flatten" labelWidth={LABEL_WIDTH} tooltip="Turn the nested objects of the messages into fields (otherwise only the top level keys are fields, and the nested objects are kept as JSON)">
+            <InlineSwitch
+                onChange={(event) => setFlattenOptions(event.currentTarget.checked ? {} : undefined)}
+                value={flattenOptions !== undefined}
+                width={SWITCH_WIDTH}
+            />
+        </InlineField>
+        {flattenOptions && (
+            <>
+                <InlineField label="Separator" labelWidth={LABEL_WIDTH} tooltip="Joins the keys of the nested objects into the field names">
+                    <Input
+                        onBlur={(event) => updateFlattenProperty('separator', event.currentTarget.value || undefined)}
+                        defaultValue={flattenOptions.separator ?? ''}
+                        placeholder="."
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Max Depth" labelWidth={LABEL_WIDTH} tooltip="The max number of keys in a field name, the deeper objects are kept as JSON (0 for no limit)">
+                    <Input
+                        type="number"
+                        onBlur={(event) => {
+                            const maxDepth = parseInt(event.currentTarget.value, 10);
+                            updateFlattenProperty('maxDepth', isNaN(maxDepth) || maxDepth <= 0 ? undefined : maxDepth);
+                        }}
+                        defaultValue={flattenOptions.maxDepth?.toString() ?? ''}
+                        placeholder="0"
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Keep as JSON" labelWidth={LABEL_WIDTH} tooltip="The field names of the objects that are kept as JSON instead of being flattened (like sensor.raw)">
+                    <TagsInput
+                        onChange={(keepJson) => updateFlattenProperty('keepJson', keepJson.length > 0 ? keepJson : undefined)}
+                        tags={flattenOptions.keepJson ?? []}
+                        placeholder="New field name (enter key to add)"
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+            </>
+        )}
+    </>
+    );
+}
@@ -0,0 +1,76 @@
+import React from 'react';
+
+import { InlineField, Input, Select } from '@grafana/ui';
+
+import { OffsetOptions } from '../types';
+import { LABEL_WIDTH, INPUT_WIDTH } from './consts';
+
+
+const offsetTypes = [
+    { label: 'First', value: 'first', description: 'The first message stored in the stream' },
+    { label: 'Last', value: 'last', description: 'The last chunk stored in the stream' },
+    { label: 'Next', value: 'next', description: 'Only the messages published after the consumer started' },
+    { label: 'Offset', value: 'offset', description: 'A specific offset in the stream' },
+    { label: 'Timestamp', value: 'timestamp', description: 'The messages stored since a point in time' },
+    { label: 'Relative Time', value: 'relativeTime', description: 'The messages stored in the last minutes/hours' },
+    { label: 'Last Consumed', value: 'lastConsumed', description: 'The offset stored by the named consumer' },
+];
+
+export function OffsetComponent({ offsetOptions, setOffsetOptions, placeholder }: { offsetOptions?: OffsetOptions, setOffsetOptions: (offsetOptions?: OffsetOptions) => void, placeholder?: string }) {
+    const updateOffsetProperty = (property: keyof OffsetOptions, value: string | number | undefined) => {
+        setOffsetOptions({ type: 'first', ...offsetOptions, [property]: value });
+    };
+
+    return (
+    <>
+        <InlineField label="Offset" labelWidth={LABEL_WIDTH} tooltip="Where the consumer starts consuming the stream">
+            <Select
+                options={offsetTypes}
+                value={offsetOptions?.type ?? null}
+                onChange={(option) => setOffsetOptions(option?.value ? { type: option.value } : undefined)}
+                placeholder={placeholder}
+                isClearable={placeholder !== undefined}
+                width={INPUT_WIDTH}
+            />
+        </InlineField>
+        {offsetOptions?.type === 'offset' && (
+            <InlineField label="Offset Value" labelWidth={LABEL_WIDTH} tooltip="The offset to start consuming from">
+                <Input
+                    type="number"
+                    onBlur={(event) => {
+                        const offset = parseInt(event.currentTarget.value, 10);
+                        updateOffsetProperty('offset', isNaN(offset) ? undefined : offset);
+                    }}
+                    defaultValue={offsetOptions.offset?.toString() ?? ''}
+                    placeholder="0"
+                    width={INPUT_WIDTH}
+                />
+            </InlineField>
+        )}
+        {offsetOptions?.type === 'timestamp' && (
+            <InlineField label="Timestamp" labelWidth={LABEL_WIDTH} tooltip="The epoch time in milliseconds to start consuming from">
+                <Input
+                    type="number"
+                    onBlur={(event) => {
+                        const timestamp = parseInt(event.currentTarget.value, 10);
+                        updateOffsetProperty('timestamp', isNaN(timestamp) ? undefined : timestamp);
+                    }}
+                    defaultValue={offsetOptions.timestamp?.toString() ?? ''}
+                    placeholder={Date.now().toString()}
+                    width={INPUT_WIDTH}
+                />
+            </InlineField>
+        )}
+        {offsetOptions?.type === 'relativeTime' && (
+            <InlineField label="Relative Time" labelWidth={LABEL_WIDTH} tooltip="How far back from now to start consuming, like 15m or 2h">
+                <Input
+                    onBlur={(event) => updateOffsetProperty('relativeTime', event.currentTarget.value || undefined)}
+                    defaultValue={offsetOptions.relativeTime ?? ''}
+                    placeholder="15m"
+                    width={INPUT_WIDTH}
+                />
+            </InlineField>
+        )}
+    </>
+    );
+}
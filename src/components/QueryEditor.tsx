@@ -1,14 +1,202 @@
 import React from 'react';
 
-import { InlineLabel } from '@grafana/ui';
-import { SHORT_TEXT_WIDTH } from './consts';
+import { InlineField, InlineSwitch, Input, RadioButtonGroup, TagsInput } from '@grafana/ui';
+import { QueryEditorProps } from '@grafana/data';
+
+import { DataSource } from '../datasource';
+import { RabbitMQQuery, RabbitMQDataSourceOptions, OffsetOptions, StreamFilterOptions, QueueConsumerOptions, FlattenOptions, Extraction, ExplodeOptions, SchemaField, TimestampOptions } from '../types';
+import { OffsetComponent } from './OffsetComponent';
+import { FilterComponent } from './FilterComponent';
+import { FlattenComponent } from './FlattenComponent';
+import { ExtractionsComponent } from './ExtractionsComponent';
+import { ExplodeComponent } from './ExplodeComponent';
+import { TimestampComponent } from './TimestampComponent';
+import { SchemaComponent } from './SchemaComponent';
+import { LABEL_WIDTH, INPUT_WIDTH, SWITCH_WIDTH } from './consts';
+
+type Props = QueryEditorProps<DataSource, RabbitMQQuery, RabbitMQDataSourceOptions>;
+
+const sources = [
+  { label: 'Stream', value: 'stream' },
+  { label: 'Queue', value: 'queue' },
+];
+
+const aggregations = [
+  { label: 'Mean', value: 'mean' },
+  { label: 'Min', value: 'min' },
+  { label: 'Max', value: 'max' },
+  { label: 'Sum', value: 'sum' },
+  { label: 'Count', value: 'count' },
+  { label: 'First', value: 'first' },
+  { label: 'Last', value: 'last' },
+];
+
+export const QueryEditor = ({ query, onChange, onRunQuery }: Props) => {
+  const updateQueryProperty = (property: keyof RabbitMQQuery, value: string | string[] | boolean | number | OffsetOptions | StreamFilterOptions | QueueConsumerOptions | FlattenOptions | Extraction[] | ExplodeOptions | SchemaField[] | TimestampOptions | undefined) => {
+    onChange({ ...query, [property]: value });
+    onRunQuery();
+  };
+
+  const updateQueueProperty = (property: keyof QueueConsumerOptions, value: string | number | boolean | undefined) => {
+    updateQueryProperty('queue', { ...query.queue, [property]: value });
+  };
 
-export const QueryEditor = () => {
   return (
     <>
-      <InlineLabel width={SHORT_TEXT_WIDTH} tooltip="The Consumer of the RabbitMQ Stream is Created Once You Press Refresh Dashboard!">
-      Ready to go!
-      </InlineLabel>
+      <InlineField label="Source" labelWidth={LABEL_WIDTH} tooltip="Consume the stream, or a classic/quorum queue over AMQP (queues have no history)">
+        <RadioButtonGroup
+          options={sources}
+          value={query.queue ? 'queue' : 'stream'}
+          onChange={(value) => updateQueryProperty('queue', value === 'queue' ? {} : undefined)}
+        />
+      </InlineField>
+      {query.queue && (
+        <>
+          <InlineField label="Queue Name" labelWidth={LABEL_WIDTH} tooltip="The existing queue to consume, its messages are taken from its other consumers (leave empty to bind a temporary queue to an exchange)">
+            <Input
+              onBlur={(event) => updateQueueProperty('queueName', event.currentTarget.value || undefined)}
+              defaultValue={query.queue.queueName ?? ''}
+              placeholder="Queue Name"
+              width={INPUT_WIDTH}
+            />
+          </InlineField>
+          <InlineField label="Take Messages" labelWidth={LABEL_WIDTH} tooltip="Allow consuming the existing queue, which takes its messages from its other consumers (required by Queue Name)">
+            <InlineSwitch
+              onChange={(event) => updateQueueProperty('takeMessages', event.currentTarget.checked)}
+              value={query.queue.takeMessages ?? false}
+              width={SWITCH_WIDTH}
+            />
+          </InlineField>
+          <InlineField label="Exchange" labelWidth={LABEL_WIDTH} tooltip="Bind an exclusive auto-deleted queue to the exchange, so the dashboard gets a copy of the messages without taking them from the real consumers">
+            <Input
+              onBlur={(event) => updateQueueProperty('exchange', event.currentTarget.value || undefined)}
+              defaultValue={query.queue.exchange ?? ''}
+              placeholder="Exchange"
+              width={INPUT_WIDTH}
+            />
+          </InlineField>
+          <InlineField label="Routing Key" labelWidth={LABEL_WIDTH} tooltip="The routing key of the binding to the exchange">
+            <Input
+              onBlur={(event) => updateQueueProperty('routingKey', event.currentTarget.value || undefined)}
+              defaultValue={query.queue.routingKey ?? ''}
+              placeholder="Routing Key (can be empty)"
+              width={INPUT_WIDTH}
+            />
+          </InlineField>
+          <InlineField label="Prefetch Count" labelWidth={LABEL_WIDTH} tooltip="The max number of unacknowledged messages delivered to the consumer (0 for no limit)">
+            <Input
+              type="number"
+              onBlur={(event) => {
+                const prefetchCount = parseInt(event.currentTarget.value, 10);
+                updateQueueProperty('prefetchCount', isNaN(prefetchCount) ? undefined : prefetchCount);
+              }}
+              defaultValue={query.queue.prefetchCount?.toString() ?? ''}
+              placeholder="0"
+              width={INPUT_WIDTH}
+            />
+          </InlineField>
+        </>
+      )}
+      <InlineField label="Stream Name" labelWidth={LABEL_WIDTH} tooltip="The stream to consume (leave empty to use the stream of the datasource)">
+        <Input
+          onBlur={(event) => updateQueryProperty('streamName', event.currentTarget.value || undefined)}
+          defaultValue={query.streamName ?? ''}
+          placeholder="Datasource Stream"
+          width={INPUT_WIDTH}
+        />
+      </InlineField>
+      <InlineField label="Super Stream" labelWidth={LABEL_WIDTH} tooltip="Is the stream a super stream, whose partitions are consumed together (the datasource setting is used until it is switched)">
+        <InlineSwitch
+          onChange={(event) => updateQueryProperty('superStream', event.currentTarget.checked)}
+          value={query.superStream ?? false}
+          width={SWITCH_WIDTH}
+        />
+      </InlineField>
+      <InlineField label="Partitions" labelWidth={LABEL_WIDTH} tooltip="The partitions of the super stream to consume (leave empty to consume all the partitions)">
+        <TagsInput
+          onChange={(partitions) => updateQueryProperty('partitions', partitions.length > 0 ? partitions : undefined)}
+          tags={query.partitions ?? []}
+          placeholder="New partition (enter key to add)"
+          width={INPUT_WIDTH}
+        />
+      </InlineField>
+      <InlineField label="Consumer Name" labelWidth={LABEL_WIDTH} tooltip="The consumer name that will be created (leave empty to use the default consumer name)">
+        <Input
+          onBlur={(event) => updateQueryProperty('consumerName', event.currentTarget.value || undefined)}
+          defaultValue={query.consumerName ?? ''}
+          placeholder="Consumer Name (can be empty)"
+          width={INPUT_WIDTH}
+        />
+      </InlineField>
+      <InlineField label="Offset From Start" labelWidth={LABEL_WIDTH} tooltip="Should the consumer consume messages from the start or the end of the stored messages in the stream (the datasource setting is used until it is switched)">
+        <InlineSwitch
+          onChange={(event) => updateQueryProperty('offsetFromStart', event.currentTarget.checked)}
+          value={query.offsetFromStart ?? false}
+          width={SWITCH_WIDTH}
+        />
+      </InlineField>
+      <OffsetComponent
+        offsetOptions={query.offsetOptions}
+        setOffsetOptions={(offsetOptions) => updateQueryProperty('offsetOptions', offsetOptions)}
+        placeholder="Offset From Start"
+      />
+      <FilterComponent
+        filterOptions={query.filter}
+        setFilterOptions={(filter) => updateQueryProperty('filter', filter)}
+      />
+      <InlineField label="CRC" labelWidth={LABEL_WIDTH} tooltip="When CRC control is disabled, the perfomance is increased (the datasource setting is used until it is switched)">
+        <InlineSwitch
+          onChange={(event) => updateQueryProperty('crc', event.currentTarget.checked)}
+          value={query.crc ?? false}
+          width={SWITCH_WIDTH}
+        />
+      </InlineField>
+      <ExplodeComponent
+        explodeOptions={query.explode}
+        setExplodeOptions={(explode) => updateQueryProperty('explode', explode)}
+      />
+      <FlattenComponent
+        flattenOptions={query.flatten}
+        setFlattenOptions={(flatten) => updateQueryProperty('flatten', flatten)}
+      />
+      <ExtractionsComponent
+        extractions={query.extractions}
+        setExtractions={(extractions) => updateQueryProperty('extractions', extractions)}
+      />
+      <SchemaComponent
+        schema={query.schema}
+        setSchema={(schema) => updateQueryProperty('schema', schema)}
+      />
+      <TimestampComponent
+        timestampOptions={query.timestamp}
+        setTimestampOptions={(timestamp) => updateQueryProperty('timestamp', timestamp)}
+      />
+      <InlineField label="Live Only" labelWidth={LABEL_WIDTH} tooltip="Skip the history of the time range, the panel only shows the messages that are streamed after it was loaded (alert rules still read the history)">
+        <InlineSwitch
+          onChange={(event) => updateQueryProperty('liveOnly', event.currentTarget.checked || undefined)}
+          value={query.liveOnly ?? false}
+          width={SWITCH_WIDTH}
+        />
+      </InlineField>
+      <InlineField label="Max Rows" labelWidth={LABEL_WIDTH} tooltip="The max number of messages read from the history of the stream in the time range of the dashboard">
+        <Input
+          type="number"
+          onBlur={(event) => {
+            const maxRows = parseInt(event.currentTarget.value, 10);
+            updateQueryProperty('maxRows', isNaN(maxRows) ? undefined : maxRows);
+          }}
+          defaultValue={query.maxRows?.toString() ?? ''}
+          placeholder="10000"
+          width={INPUT_WIDTH}
+        />
+      </InlineField>
+      <InlineField label="Aggregation" labelWidth={LABEL_WIDTH} tooltip="How the numeric values of the history are aggregated when there are more messages than data points in the panel (and for alert rules)">
+        <RadioButtonGroup
+          options={aggregations}
+          value={query.aggregation ?? 'mean'}
+          onChange={(value) => updateQueryProperty('aggregation', value)}
+        />
+      </InlineField>
     </>
   );
 };
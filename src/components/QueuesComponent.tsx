@@ -0,0 +1,192 @@
+import React from 'react';
+
+import { InlineField, InlineSwitch, Input, Button, RadioButtonGroup } from '@grafana/ui';
+
+import { QueuesOptions, QueueOptions, Arguments } from '../types';
+import { ArgumentsComponent } from './ArgumentsComponent';
+import { LABEL_WIDTH, INPUT_WIDTH, SWITCH_WIDTH } from './consts';
+
+
+export function QueuesComponent({ queues, setQueues }: { queues: QueuesOptions, setQueues: React.Dispatch<React.SetStateAction<QueuesOptions>>}) {
+    const DEFAULT_SHOULD_DISPOSE_QUEUE = false;
+    const DEFAULT_DISPOSE_IF_UNUSED = true;
+    const DEFAULT_DISPOSE_IF_EMPTY = true;
+    const DEFAULT_NAME = "rabbitmq.queue";
+    const DEFAULT_TYPE = "classic";
+    const DEFAULT_DURABLE = true;
+    const DEFAULT_AUTO_DELETED = false;
+    const DEFAULT_EXCLUSIVE = false;
+    const DEFAULT_NO_WAIT = false;
+
+    const addQueue = () => {
+        const newQueue: QueueOptions = {
+            shouldDisposeQueue: DEFAULT_SHOULD_DISPOSE_QUEUE,
+            disposeIfUnused: DEFAULT_DISPOSE_IF_UNUSED,
+            disposeIfEmpty: DEFAULT_DISPOSE_IF_EMPTY,
+            name: DEFAULT_NAME,
+            type: DEFAULT_TYPE,
+            durable: DEFAULT_DURABLE,
+            autoDeleted: DEFAULT_AUTO_DELETED,
+            exclusive: DEFAULT_EXCLUSIVE,
+            noWait: DEFAULT_NO_WAIT
+        }
+
+        setQueues(prevQueues => [...prevQueues, newQueue]);
+    }
+
+    const queueTypes = [{
+        label: 'Classic',
+        value: 'classic'
+      }, {
+        label: 'Quorum',
+        value: 'quorum'
+      }, {
+        label: 'Stream',
+        value: 'stream'
+      },
+    ];
+
+    const overflowTypes = [{
+        label: 'Default',
+        value: ''
+      }, {
+        label: 'Drop Head',
+        value: 'drop-head'
+      }, {
+        label: 'Reject Publish',
+        value: 'reject-publish'
+      }, {
+        label: 'Reject Publish DLX',
+        value: 'reject-publish-dlx'
+      },
+    ];
+
+    const updateQueueProperty = (index: number, property: keyof QueueOptions, value: string | number | boolean | Arguments | undefined) => {
+        setQueues(prevQueues =>
+          prevQueues.map((queue, i) => (i === index ? { ...queue, [property]: value } : queue))
+        );
+      };
+
+    const updateNumericQueueProperty = (index: number, property: keyof QueueOptions, value: string) => {
+        const parsedValue = parseInt(value, 10);
+        updateQueueProperty(index, property, isNaN(parsedValue) ? undefined : parsedValue);
+    };
+
+    const removeQueue = (index: number) => {
+        setQueues(prevQueues => prevQueues.filter((_, i) => i !== index));
+    }
+
+    return (
+    <>
+        {
+        queues.map((value, index) => (
+            <>
+                <InlineField label="Should Dispose Queue" labelWidth={LABEL_WIDTH} tooltip="Should delete this queue when the RabbitMQ datasource is deleted">
+                    <InlineSwitch
+                        onChange={ event => updateQueueProperty(index, 'shouldDisposeQueue', event.currentTarget.checked)}
+                        value={value.shouldDisposeQueue}
+                        width={SWITCH_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Dispose if Unused" labelWidth={LABEL_WIDTH} tooltip="Delete this queue only if it doesn't have consumers (and if 'Should Dispose Queue' is set ON)">
+                    <InlineSwitch
+                        onChange={ event => updateQueueProperty(index, 'disposeIfUnused', event.currentTarget.checked)}
+                        value={value.disposeIfUnused}
+                        width={SWITCH_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Dispose if Empty" labelWidth={LABEL_WIDTH} tooltip="Delete this queue only if it doesn't have messages (and if 'Should Dispose Queue' is set ON)">
+                    <InlineSwitch
+                        onChange={ event => updateQueueProperty(index, 'disposeIfEmpty', event.currentTarget.checked)}
+                        value={value.disposeIfEmpty}
+                        width={SWITCH_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Queue Name" labelWidth={LABEL_WIDTH} tooltip="The queue name that should exist in the RabbitMQ">
+                    <Input
+                        onChange={ event => updateQueueProperty(index, 'name', event.currentTarget.value || DEFAULT_NAME)}
+                        value={value.name}
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Queue Type" labelWidth={LABEL_WIDTH} tooltip="The queue type (quorum and stream queues must be durable, and can't be auto deleted or exclusive)">
+                    <RadioButtonGroup options={queueTypes} value={value.type} onChange={event => updateQueueProperty(index, 'type', event)} />
+                </InlineField>
+                <InlineField label="Is Durable" labelWidth={LABEL_WIDTH} tooltip="Should the queue be durable">
+                    <InlineSwitch
+                        onChange={ event => updateQueueProperty(index, 'durable', event.currentTarget.checked)}
+                        value={value.durable}
+                        width={SWITCH_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Is Auto Deleted" labelWidth={LABEL_WIDTH} tooltip="Should the queue be auto deleted">
+                    <InlineSwitch
+                        onChange={ event => updateQueueProperty(index, 'autoDeleted', event.currentTarget.checked)}
+                        value={value.autoDeleted}
+                        width={SWITCH_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Is Exclusive" labelWidth={LABEL_WIDTH} tooltip="Should the queue be exclusive">
+                    <InlineSwitch
+                        onChange={ event => updateQueueProperty(index, 'exclusive', event.currentTarget.checked)}
+                        value={value.exclusive}
+                        width={SWITCH_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Is No Wait" labelWidth={LABEL_WIDTH} tooltip="Should the queue be noWait">
+                    <InlineSwitch
+                        onChange={ event => updateQueueProperty(index, 'noWait', event.currentTarget.checked)}
+                        value={value.noWait}
+                        width={SWITCH_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Message TTL" labelWidth={LABEL_WIDTH} tooltip="The time to live of the messages in milliseconds (x-message-ttl, leave empty for no TTL)">
+                    <Input
+                        onChange={ event => updateNumericQueueProperty(index, 'messageTtl', event.currentTarget.value)}
+                        value={value.messageTtl?.toString() ?? ''}
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Max Length" labelWidth={LABEL_WIDTH} tooltip="The max number of messages in the queue (x-max-length, leave empty for no limit)">
+                    <Input
+                        onChange={ event => updateNumericQueueProperty(index, 'maxLength', event.currentTarget.value)}
+                        value={value.maxLength?.toString() ?? ''}
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Max Length Bytes" labelWidth={LABEL_WIDTH} tooltip="The max size of the messages in the queue in bytes (x-max-length-bytes, leave empty for no limit)">
+                    <Input
+                        onChange={ event => updateNumericQueueProperty(index, 'maxLengthBytes', event.currentTarget.value)}
+                        value={value.maxLengthBytes?.toString() ?? ''}
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Overflow" labelWidth={LABEL_WIDTH} tooltip="What happens once the queue reached its max length (x-overflow)">
+                    <RadioButtonGroup options={overflowTypes} value={value.overflow ?? ''} onChange={event => updateQueueProperty(index, 'overflow', event || undefined)} />
+                </InlineField>
+                <InlineField label="Dead Letter Exchange" labelWidth={LABEL_WIDTH} tooltip="The exchange of the rejected and expired messages (x-dead-letter-exchange)">
+                    <Input
+                        onChange={ event => updateQueueProperty(index, 'deadLetterExchange', event.currentTarget.value || undefined)}
+                        value={value.deadLetterExchange ?? ''}
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Dead Letter Routing Key" labelWidth={LABEL_WIDTH} tooltip="The routing key of the dead lettered messages (x-dead-letter-routing-key, leave empty to keep their routing key)">
+                    <Input
+                        onChange={ event => updateQueueProperty(index, 'deadLetterRoutingKey', event.currentTarget.value || undefined)}
+                        value={value.deadLetterRoutingKey ?? ''}
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <ArgumentsComponent
+                    args={value.arguments}
+                    setArgs={args => updateQueueProperty(index, 'arguments', args)}
+                    tooltip="Any other argument of the queue, as a JSON object"
+                />
+                <Button variant="secondary" fill="text" icon="minus" onClick={() => removeQueue(index)} tooltip="Remove" aria-label="Remove" />
+            </>
+            ))
+        }
+        <Button variant="secondary" fill="text" icon="plus" onClick={addQueue} tooltip="Add" aria-label="Add" />
+    </>)
+};
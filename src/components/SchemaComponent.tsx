@@ -0,0 +1,80 @@
+import React from 'react';
+
+import { InlineField, InlineSwitch, Input, Button, RadioButtonGroup } from '@grafana/ui';
+
+import { SchemaField } from '../types';
+import { fieldTypes } from './ExtractionsComponent';
+import { LABEL_WIDTH, INPUT_WIDTH, SWITCH_WIDTH } from './consts';
+
+
+const schemaFieldTypes = fieldTypes.filter(fieldType => fieldType.value !== 'auto');
+
+export function SchemaComponent({ schema, setSchema }: { schema?: SchemaField[], setSchema: (schema?: SchemaField[]) => void }) {
+    const DEFAULT_NAME = "value";
+    const DEFAULT_TYPE = "number";
+    const DEFAULT_NULLABLE = true;
+
+    const addSchemaField = () => {
+        setSchema([...(schema ?? []), { name: DEFAULT_NAME, type: DEFAULT_TYPE, nullable: DEFAULT_NULLABLE }]);
+    }
+
+    const updateSchemaFieldProperty = (index: number, property: keyof SchemaField, value: string | boolean | undefined) => {
+        setSchema((schema ?? []).map((schemaField, i) => (i === index ? { ...schemaField, [property]: value } : schemaField)));
+    };
+
+    const removeSchemaField = (index: number) => {
+        const remainingSchema = (schema ?? []).filter((_, i) => i !== index);
+        setSchema(remainingSchema.length > 0 ? remainingSchema : undefined);
+    }
+
+    return (
+    <>
+        {
+        (schema ?? []).map((value, index) => (
+            <>
+                <InlineField label="Field Name" labelWidth={LABEL_WIDTH} tooltip="The name of the field (the flattened name or the alias of an extraction)">
+                    <Input
+                        onBlur={ event => updateSchemaFieldProperty(index, 'name', event.currentTarget.value || DEFAULT_NAME)}
+                        defaultValue={value.name}
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Field Type" labelWidth={LABEL_WIDTH} tooltip="The values are converted to the type, a time is parsed from an RFC3339 string or from milliseconds since the epoch">
+                    <RadioButtonGroup
+                        options={schemaFieldTypes}
+                        value={value.type}
+                        onChange={ type => updateSchemaFieldProperty(index, 'type', type)}
+                    />
+                </InlineField>
+                <InlineField label="Unit" labelWidth={LABEL_WIDTH} tooltip="The unit of the field, like celsius or bytes (can be empty)">
+                    <Input
+                        onBlur={ event => updateSchemaFieldProperty(index, 'unit', event.currentTarget.value || undefined)}
+                        defaultValue={value.unit ?? ''}
+                        placeholder="Unit (can be empty)"
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Nullable" labelWidth={LABEL_WIDTH} tooltip="Leave the missing and rejected values empty when there is no default value (otherwise they are the zero value of the type, and a time field must have a default)">
+                    <InlineSwitch
+                        onChange={ event => updateSchemaFieldProperty(index, 'nullable', event.currentTarget.checked)}
+                        value={value.nullable ?? false}
+                        width={SWITCH_WIDTH}
+                    />
+                </InlineField>
+                <InlineField label="Default" labelWidth={LABEL_WIDTH} tooltip="Replaces the missing and rejected values, it is converted to the type of the field (can be empty)">
+                    <Input
+                        onBlur={ event => updateSchemaFieldProperty(index, 'default', event.currentTarget.value || undefined)}
+                        defaultValue={value.default?.toString() ?? ''}
+                        placeholder="Default (can be empty)"
+                        width={INPUT_WIDTH}
+                    />
+                </InlineField>
+                <Button variant="secondary" fill="text" icon="minus" onClick={() => removeSchemaField(index)} tooltip="Remove" aria-label="Remove" />
+            </>
+            ))
+        }
+        <InlineField label="Schema" labelWidth={LABEL_WIDTH} tooltip="Declare the types of the fields, so their values are converted instead of being dropped when they arrive with another type">
+            <Button variant="secondary" fill="text" icon="plus" onClick={addSchemaField} tooltip="Add" aria-label="Add" />
+        </InlineField>
+    </>)
+};
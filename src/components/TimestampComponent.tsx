@@ -0,0 +1,58 @@
+import React from 'react';
+
+import { InlineField, Input, Select } from '@grafana/ui';
+
+import { TimestampOptions } from '../types';
+import { LABEL_WIDTH, INPUT_WIDTH } from './consts';
+
+
+const timestampSources = [
+    { label: 'Consume Time', value: 'consume', description: 'The time the message was consumed by the plugin' },
+    { label: 'Creation Time', value: 'creationTime', description: 'The creation-time property of a stream message, or the timestamp property of a queue message' },
+    { label: 'Payload Field', value: 'field', description: 'A field of the payload of the message' },
+];
+
+const timestampLayouts = [
+    { label: 'RFC3339', value: 'rfc3339', description: 'A string like 2024-01-02T03:04:05.123Z' },
+    { label: 'Epoch Seconds', value: 'epochSeconds' },
+    { label: 'Epoch Milliseconds', value: 'epochMillis' },
+    { label: 'Epoch Microseconds', value: 'epochMicros' },
+    { label: 'Epoch Nanoseconds', value: 'epochNanos' },
+];
+
+export function TimestampComponent({ timestampOptions, setTimestampOptions }: { timestampOptions?: TimestampOptions, setTimestampOptions: (timestampOptions?: TimestampOptions) => void }) {
+    return (
+    <>
+        <InlineField label="Timestamp" labelWidth={LABEL_WIDTH} tooltip="Where the time of the rows comes from, the rows without a timestamp fall back to the time the message was consumed">
+            <Select
+                options={timestampSources}
+                value={timestampOptions?.source ?? 'consume'}
+                onChange={(option) => setTimestampOptions(option?.value && option.value !== 'consume' ? { source: option.value } : undefined)}
+                width={INPUT_WIDTH}
+            />
+        </InlineField>
+        {timestampOptions?.source === 'field' && (
+        <>
+            <InlineField label="Timestamp Field" labelWidth={LABEL_WIDTH} tooltip="A JSONPath expression of the timestamp in the payload, like $.ts (evaluated on every row of an exploded array)">
+                <Input
+                    onBlur={(event) => setTimestampOptions({ ...timestampOptions, field: event.currentTarget.value || undefined })}
+                    defaultValue={timestampOptions.field ?? ''}
+                    placeholder="$.timestamp"
+                    width={INPUT_WIDTH}
+                />
+            </InlineField>
+            <InlineField label="Timestamp Layout" labelWidth={LABEL_WIDTH} tooltip="The format of the timestamp field, by default the strings are RFC3339 timestamps and the numbers are epoch milliseconds">
+                <Select
+                    options={timestampLayouts}
+                    value={timestampOptions.layout ?? null}
+                    onChange={(option) => setTimestampOptions({ ...timestampOptions, layout: option?.value })}
+                    placeholder="Auto"
+                    isClearable
+                    width={INPUT_WIDTH}
+                />
+            </InlineField>
+        </>
+        )}
+    </>
+    );
+}
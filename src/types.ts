@@ -1,33 +1,143 @@
 import { DataSourceJsonData } from '@grafana/data';
 import { DataQuery } from '@grafana/schema';
 
-export interface RabbitMQQuery extends DataQuery { }
+export interface RabbitMQQuery extends DataQuery {
+  streamName?: string;
+  superStream?: boolean;
+  partitions?: string[];
+  consumerName?: string;
+  offsetFromStart?: boolean;
+  crc?: boolean;
+  offsetOptions?: OffsetOptions;
+  filter?: StreamFilterOptions;
+  queue?: QueueConsumerOptions;
+  flatten?: FlattenOptions;
+  extractions?: Extraction[];
+  explode?: ExplodeOptions;
+  schema?: SchemaField[];
+  timestamp?: TimestampOptions;
+  liveOnly?: boolean;
+  maxRows?: number;
+  aggregation?: string;
+}
 
 export interface StreamOptions {
   shouldDisposeStream: boolean;
   streamName: string;
   consumerName: string;
   offsetFromStart: boolean;
+  offsetOptions?: OffsetOptions;
   maxAge: number;
   maxLengthBytes: number;
   maxSegmentSizeBytes: number;
   crc: boolean;
+  offsetStorage?: OffsetStorageOptions;
+  preferReplica?: boolean;
+  singleActiveConsumer?: boolean;
+  filter?: StreamFilterOptions;
+  superStream?: boolean;
+  partitionCount?: number;
+  partitions?: string[];
+}
+
+export interface QueueConsumerOptions {
+  queueName?: string;
+  takeMessages?: boolean;
+  exchange?: string;
+  routingKey?: string;
+  prefetchCount?: number;
+}
+
+export interface FlattenOptions {
+  separator?: string;
+  maxDepth?: number;
+  keepJson?: string[];
+}
+
+export interface SchemaField {
+  name: string;
+  type: string;
+  unit?: string;
+  nullable?: boolean;
+  default?: string | number | boolean;
+}
+
+export interface ExplodeOptions {
+  path?: string;
+}
+
+export interface TimestampOptions {
+  source?: string;
+  field?: string;
+  layout?: string;
+}
+
+export interface Extraction {
+  expression: string;
+  language?: string;
+  alias?: string;
+  type?: string;
+}
+
+export interface StreamFilterOptions {
+  values: string[];
+  matchUnfiltered?: boolean;
+  property?: string;
+}
+
+export interface OffsetStorageOptions {
+  mode: string;
+  messageCount?: number;
+  intervalSeconds?: number;
+}
+
+export interface OffsetOptions {
+  type: string;
+  offset?: number;
+  timestamp?: number;
+  relativeTime?: string;
+}
+
+export interface LiveOptions {
+  slowSubscriberPolicy: string;
+  subscriberBufferSize: number;
+}
+
+export interface ReconnectOptions {
+  initialIntervalMs: number;
+  maxIntervalMs: number;
+  multiplier: number;
+  jitter: number;
+  maxAttempts: number;
+  maxElapsedSeconds: number;
 }
 
 export interface RabbitMQDataSourceOptions extends DataSourceJsonData {
   host: string;
+  endpoints?: string[];
+  loadBalancerMode?: boolean;
   amqpPort: number;
   streamPort: number;
   vHost: string;
 
   tlsConnection?: boolean;
+  tlsServerName?: string;
+  tlsSkipVerify?: boolean;
+  authMode?: string;
   username: string;
+  oauth2TokenUrl?: string;
+  oauth2ClientId?: string;
+  oauth2Scopes?: string;
 
   streamOptions: StreamOptions;
 
   exchangesOptions: ExchangesOptions;
+  queuesOptions?: QueuesOptions;
   bindingsOptions: BindingsOptions;
 
+  liveOptions?: LiveOptions;
+  reconnectOptions?: ReconnectOptions;
+
   requestedHeartbeat: number;
   requestedMaxFrameSize: number;
   writeBuffer: number;
@@ -44,10 +154,37 @@ export interface ExchangeOptions {
   autoDeleted: boolean;
   internal: boolean;
   noWait: boolean;
+  alternateExchange?: string;
+  arguments?: Arguments;
 }
 
 export interface ExchangesOptions extends Array<ExchangeOptions> {}
 
+export interface QueueOptions {
+  shouldDisposeQueue: boolean;
+  disposeIfUnused: boolean;
+  disposeIfEmpty: boolean;
+  name: string;
+  type: string;
+  durable: boolean;
+  autoDeleted: boolean;
+  exclusive: boolean;
+  noWait: boolean;
+  messageTtl?: number;
+  maxLength?: number;
+  maxLengthBytes?: number;
+  deadLetterExchange?: string;
+  deadLetterRoutingKey?: string;
+  overflow?: string;
+  arguments?: Arguments;
+}
+
+export interface QueuesOptions extends Array<QueueOptions> {}
+
+export interface Arguments {
+  [key: string]: unknown;
+}
+
 export interface BindingOptions {
   shouldDisposeBinding: boolean;
   isQueueBinding: boolean;
@@ -55,10 +192,15 @@ export interface BindingOptions {
   routingKey: string;
   receiverName: string;
   noWait: boolean;
+  arguments?: Arguments;
 }
 
 export interface BindingsOptions extends Array<BindingOptions> {}
 
 export interface RabbitMQSecureJsonData {
   password?: string;
+  tlsCACert?: string;
+  tlsClientCert?: string;
+  tlsClientKey?: string;
+  oauth2ClientSecret?: string;
 }